@@ -0,0 +1,186 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/notify"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+	"github.com/rs/zerolog/log"
+)
+
+// Errors returned by Ack.
+var (
+	ErrNotOpen = errors.New("alert is not open")
+	ErrAcked   = errors.New("alert is already acknowledged")
+)
+
+// Ack marks an open alert as acknowledged by someone. id is the alert ID, the
+// key of the alert or its hash (see komodo.HashKey). Further alerts about it
+// are suppressed until it is resolved. If it is already acknowledged, the
+// alert is returned with ErrAcked.
+func (a *Alerter) Ack(id, by string) (*OpenAlert, error) {
+	a.openMu.Lock()
+	defer a.openMu.Unlock()
+
+	var found *OpenAlert
+	err := a.DB.ForEach(openBucket, func(key string, val []byte) error {
+		var o OpenAlert
+		if err := json.Unmarshal(val, &o); err != nil {
+			return nil
+		}
+		if o.AlertID == id || o.Key == id || komodo.HashKey(o.Key) == id {
+			found = &o
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNotOpen
+	}
+	if found.AckedBy != "" {
+		return found, ErrAcked
+	}
+
+	found.AckedBy = by
+	found.AckedAt = time.Now()
+	if err := a.DB.Put(openBucket, found.Key, found); err != nil {
+		return nil, err
+	}
+	log.Info().
+		Str("alert_id", found.AlertID).
+		Str("key", found.Key).
+		Str("by", by).
+		Msg("alert acknowledged")
+	return found, nil
+}
+
+// acked reports whether the open alert with key is acknowledged.
+func (a *Alerter) acked(key string) bool {
+	a.openMu.Lock()
+	defer a.openMu.Unlock()
+
+	var o OpenAlert
+	if ok, err := a.DB.Get(openBucket, key, &o); !ok || err != nil {
+		return false
+	}
+	return o.AckedBy != ""
+}
+
+// FindOpen returns the open alert whose key hashes to hash, see
+// komodo.HashKey.
+func (a *Alerter) FindOpen(hash string) (*OpenAlert, error) {
+	list, err := a.OpenAlerts()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range list {
+		if komodo.HashKey(o.Key) == hash {
+			return &o, nil
+		}
+	}
+	return nil, ErrNotOpen
+}
+
+// AnnounceAck appends the acknowledged footer to the messages sent for o, so
+// everyone in the chats can see who is handling it. Notifiers which cannot
+// edit messages are skipped.
+func (a *Alerter) AnnounceAck(ctx context.Context, o *OpenAlert) {
+	for i, m := range o.Messages {
+		l := log.With().
+			Str("alert_id", o.AlertID).
+			Str("notifier", m.Notifier).
+			Int64("chat", m.ChatID).
+			Logger()
+		ch, ok := a.Channels[m.Notifier]
+		if !ok {
+			continue
+		}
+		editor, ok := ch.Notifier.(notify.Editor)
+		if !ok || m.Ref == "" {
+			continue
+		}
+
+		footer, err := ch.Renderer.RenderAcked(&tmpl.Ack{
+			Type: o.Type,
+			By:   o.AckedBy,
+			At:   o.AckedAt,
+		})
+		if err != nil {
+			l.Error().Err(err).Msg("failed to render acknowledged footer")
+			continue
+		}
+		msg := &queue.Message{
+			AlertID:  o.AlertID,
+			Alert:    o.alert(),
+			Notifier: m.Notifier,
+			ChatID:   m.ChatID,
+			ThreadID: m.ThreadID,
+			Format:   m.Format,
+			Acked:    true,
+		}
+		text := strings.TrimRight(m.Text, "\n") + "\n\n" + footer
+		if err := editor.Edit(ctx, msg, m.Ref, text); err != nil {
+			l.Warn().Err(err).Msg("failed to show acknowledgement in message")
+			continue
+		}
+		o.Messages[i].Text = text
+		a.updateText(o.Key, &o.Messages[i])
+	}
+}
+
+// alert rebuilds the parts of the alert needed to edit its messages.
+func (o *OpenAlert) alert() komodo.AlertInfo {
+	return komodo.AlertInfo{
+		Timestamp: o.IssuedAt.UnixMilli(),
+		Level:     o.Level,
+		Target:    o.Target,
+		Data:      komodo.AlertData{Type: o.Type},
+	}
+}
+
+// updateText saves the edited text of a message sent for the open alert with
+// key, so resolving it keeps the edit.
+func (a *Alerter) updateText(key string, m *SentMessage) {
+	a.openMu.Lock()
+	defer a.openMu.Unlock()
+
+	var o OpenAlert
+	if ok, err := a.DB.Get(openBucket, key, &o); !ok || err != nil {
+		return
+	}
+	for i := range o.Messages {
+		if o.Messages[i].Notifier == m.Notifier &&
+			o.Messages[i].ChatID == m.ChatID &&
+			o.Messages[i].ThreadID == m.ThreadID {
+			o.Messages[i].Text = m.Text
+		}
+	}
+	if err := a.DB.Put(openBucket, key, &o); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to update open alert")
+	}
+}
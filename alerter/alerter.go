@@ -0,0 +1,260 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package alerter receives alerts from Komodo and hands them to the delivery
+// queue.
+package alerter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/archive"
+	"github.com/raohwork/komodo-tg-alerter/dedup"
+	"github.com/raohwork/komodo-tg-alerter/group"
+	"github.com/raohwork/komodo-tg-alerter/heartbeat"
+	"github.com/raohwork/komodo-tg-alerter/history"
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/metrics"
+	"github.com/raohwork/komodo-tg-alerter/notify"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/silence"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+)
+
+// Channel is a notifier and the renderer producing messages for it.
+type Channel struct {
+	Notifier notify.Notifier
+	Renderer *tmpl.Renderer
+}
+
+type Alerter struct {
+	Queue *queue.Queue
+	DB    *store.DB
+	// notifiers by name, see notify.TelegramName
+	Channels map[string]*Channel
+	Routes   []route.Route
+	// destinations used when no route matches
+	DefaultRoute []route.Destination
+	// how long the webhook waits for the first delivery attempt, 0 to
+	// respond as soon as the alert is queued
+	Wait time.Duration
+	// one of ResolveEdit, ResolveReply and ResolveSend
+	ResolveMode string
+	// suppresses duplicated and flapping alerts, nil to deliver everything
+	Dedup *dedup.Filter
+	// mutes alerts during maintenance, nil to deliver everything
+	Silences *silence.Manager
+	// holds alerts for digests, nil to deliver them one by one; see
+	// NewGrouper
+	Groups *group.Grouper
+	// records received alerts, nil to forget them
+	History *history.History
+	// keeps raw request bodies for replaying, nil to not keep them
+	Archive *archive.Archive
+	// notices when Komodo stops sending anything, nil to not watch it
+	Heartbeat *heartbeat.Monitor
+	// sends test alerts, which leave the state of real ones alone: the
+	// deduplication state is not updated, and messages are
+	// neither tracked as open alerts nor edited when resolved
+	Test bool
+
+	openMu sync.Mutex
+}
+
+// Errors returned by Dispatch wrap one of these to tell where it failed.
+var (
+	ErrRender     = errors.New("render")
+	ErrQueue      = errors.New("queue")
+	ErrSuppressed = errors.New("suppressed")
+	ErrSilenced   = errors.New("silenced")
+	ErrGrouped    = errors.New("grouped")
+)
+
+var idSeq atomic.Uint64
+
+func newAlertID() string {
+	return fmt.Sprintf("%016x%04x", time.Now().UnixNano(), uint16(idSeq.Add(1)))
+}
+
+// render renders data with the renderer of ch, or the flapping notice if
+// flapping is set.
+func render(ch *Channel, data *komodo.AlertInfo, flapping bool) (text, footer string, err error) {
+	if flapping {
+		text, err = ch.Renderer.RenderFlapping(data)
+		return
+	}
+
+	text, err = ch.Renderer.Render(data)
+	if err != nil || !data.Resolved {
+		return
+	}
+
+	footer, err = ch.Renderer.RenderResolved(data)
+	if err != nil {
+		return
+	}
+	text = strings.TrimRight(text, "\n") + "\n\n" + footer
+	return
+}
+
+// Dispatch renders data and queues it for every destination selected by the
+// routes, returning the ID assigned to the alert and the queued jobs. If the
+// messages cannot be queued, none is. The alert and the result are recorded
+// in History.
+func (a *Alerter) Dispatch(data *komodo.AlertInfo) (string, []*queue.Job, error) {
+	id := newAlertID()
+	a.recordAlert(id, data)
+
+	msgs, grouped, err := a.prepare(id, data)
+	var jobs []*queue.Job
+	if err == nil {
+		// nothing is queued if any message fails, or the retry of the
+		// webhook would duplicate those queued
+		var perr error
+		if jobs, perr = a.Queue.PushAll(msgs); perr != nil {
+			err = fmt.Errorf("%w: %w", ErrQueue, perr)
+		}
+	}
+	if a.Dedup != nil && !a.Test && (err == nil || errors.Is(err, ErrGrouped)) {
+		a.Dedup.Check(data)
+	}
+
+	a.recordDispatch(id, msgs, len(jobs), grouped, err)
+	return id, jobs, err
+}
+
+// Messages renders data for every destination selected by the routes like
+// Dispatch, but returns the messages instead of queueing them. Nothing is
+// recorded in History.
+func (a *Alerter) Messages(data *komodo.AlertInfo) ([]queue.Message, error) {
+	msgs, _, err := a.prepare(newAlertID(), data)
+	return msgs, err
+}
+
+// prepare renders data for every destination selected by the routes, except
+// those holding it for a digest, which are returned as grouped.
+func (a *Alerter) prepare(id string, data *komodo.AlertInfo) (msgs []queue.Message, grouped []route.Delivery, err error) {
+	if a.Silences != nil {
+		if s, ok := a.Silences.Match(data); ok {
+			metrics.Suppressed.WithLabelValues("silenced").Inc()
+			return nil, nil, fmt.Errorf("%w by %s", ErrSilenced, s.ID)
+		}
+	}
+
+	if !data.Resolved && a.acked(data.Key()) {
+		metrics.Suppressed.WithLabelValues("acknowledged").Inc()
+		return nil, nil, fmt.Errorf("%w: acknowledged", ErrSuppressed)
+	}
+
+	flapping := false
+	if a.Dedup != nil {
+		// recorded by Dispatch once the alert is queued, so it is not taken
+		// as sent if it fails before that
+		switch res := a.Dedup.Peek(data); res {
+		case dedup.Duplicate:
+			metrics.Suppressed.WithLabelValues("duplicate").Inc()
+			return nil, nil, fmt.Errorf("%w: %s", ErrSuppressed, res)
+		case dedup.StillFlapping:
+			metrics.Suppressed.WithLabelValues("flapping").Inc()
+			return nil, nil, fmt.Errorf("%w: %s", ErrSuppressed, res)
+		case dedup.Flapping:
+			flapping = true
+		}
+	}
+
+	dests, err := route.Resolve(a.Routes, a.DefaultRoute, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msgs = make([]queue.Message, 0, len(dests))
+	for _, d := range dests {
+		ch, ok := a.Channels[d.Notifier]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: unknown notifier %s", ErrRender, d.Notifier)
+		}
+		if a.Groups != nil && !flapping && a.Groups.Add(d, id, data) {
+			grouped = append(grouped, d)
+			continue
+		}
+
+		msg := queue.Message{
+			AlertID:  id,
+			Alert:    *data,
+			Route:    d.Route,
+			Notifier: d.Notifier,
+			ChatID:   d.Chat,
+			ThreadID: d.Thread,
+			Flapping: flapping,
+			Format:   string(ch.Renderer.Format(data.Data.Type)),
+		}
+		msg.Text, msg.Footer, err = render(ch, data, flapping)
+		if err != nil {
+			metrics.RenderFailures.WithLabelValues(d.Notifier, data.Data.Type).Inc()
+			return nil, nil, fmt.Errorf("%w: %w", ErrRender, err)
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 && len(grouped) > 0 {
+		return nil, grouped, fmt.Errorf("%w: waiting for digest", ErrGrouped)
+	}
+	return msgs, grouped, nil
+}
+
+// Deliver sends a queued job with its notifier. It is a queue.Handler.
+func (a *Alerter) Deliver(ctx context.Context, job *queue.Job) error {
+	msg := &job.Message
+	ch, ok := a.Channels[msg.Notifier]
+	if !ok {
+		return queue.Permanent(fmt.Errorf("unknown notifier %s", msg.Notifier))
+	}
+
+	ref, err := a.deliver(ctx, ch, msg)
+	a.recordDelivery(job, ref, err)
+	metrics.Deliveries.WithLabelValues(msg.Notifier, notify.ErrorClass(err)).Inc()
+	if err == nil {
+		now := time.Now()
+		metrics.DeliveryLatency.WithLabelValues(msg.Notifier).Observe(now.Sub(job.CreatedAt).Seconds())
+		metrics.LastDelivery.WithLabelValues(msg.Notifier).Set(float64(now.Unix()))
+	}
+	return err
+}
+
+// deliver sends msg with the notifier of ch, returning the ID of the message
+// sent or updated.
+func (a *Alerter) deliver(ctx context.Context, ch *Channel, msg *queue.Message) (string, error) {
+	if a.Test || msg.Flapping || msg.Digest > 0 || msg.Report || msg.Heartbeat {
+		return ch.Notifier.Send(ctx, msg)
+	}
+	if msg.Alert.Resolved {
+		return a.deliverResolved(ctx, ch.Notifier, msg)
+	}
+
+	ref, err := ch.Notifier.Send(ctx, msg)
+	if err == nil {
+		a.track(msg, ref)
+	}
+	return ref, err
+}
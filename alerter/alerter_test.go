@@ -0,0 +1,82 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/dedup"
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+)
+
+func newTestAlerter(t *testing.T) *Alerter {
+	t.Helper()
+	db, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := queue.New(db, time.Second, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Alerter{
+		Queue: q,
+		DB:    db,
+		Channels: map[string]*Channel{
+			"hook": {Renderer: tmpl.NewRenderer(nil, time.UTC, tmpl.FormatPlain)},
+		},
+		DefaultRoute: []route.Destination{{Notifier: "hook"}},
+		Dedup:        dedup.New(dedup.Config{Window: time.Hour}, db),
+	}
+}
+
+func TestDispatchDedupAfterRenderFailure(t *testing.T) {
+	a := newTestAlerter(t)
+	good := a.Channels["hook"].Renderer
+	a.Channels["hook"].Renderer = tmpl.NewRenderer(fstest.MapFS{
+		"ServerCpu.txt": {Data: []byte(`{{ index .Typed.Name 100 }}`)},
+	}, time.UTC, tmpl.FormatPlain)
+
+	data := &komodo.AlertInfo{
+		Level:  "WARNING",
+		Target: komodo.AlertTarget{Type: "Server", ID: "server-1"},
+		Data:   komodo.NewAlertData(komodo.ServerCpu{Server: komodo.Server{ID: "server-1", Name: "web"}, Percentage: 95}),
+	}
+	if _, _, err := a.Dispatch(data); !errors.Is(err, ErrRender) {
+		t.Fatalf("Dispatch with a broken template returned %v, want %v", err, ErrRender)
+	}
+
+	// Komodo retries after the template is fixed
+	a.Channels["hook"].Renderer = good
+	_, jobs, err := a.Dispatch(data)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("retry returned %d jobs, %v; want it queued", len(jobs), err)
+	}
+
+	// the queued one counts for deduplication
+	if _, _, err := a.Dispatch(data); !errors.Is(err, ErrSuppressed) {
+		t.Errorf("duplicate of a queued alert returned %v, want %v", err, ErrSuppressed)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Headers used by HMAC authentication.
+const (
+	TimestampHeader = "X-KTA-Timestamp"
+	SignatureHeader = "X-KTA-Signature"
+)
+
+// maxBodySize limits the size of request bodies.
+const maxBodySize = 1 << 20
+
+// Auth authenticates webhook requests. Every configured method must pass; if
+// nothing is configured, all requests are accepted.
+type Auth struct {
+	// expected token in "Authorization: Bearer <token>"
+	Bearer string
+	// expected request path without leading slash
+	PathSecret string
+	// key of HMAC-SHA256 over "<timestamp>.<body>"
+	HMACSecret string
+	// how far the timestamp may be from now
+	MaxSkew time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func (a *Auth) Enabled() bool {
+	return a.Bearer != "" || a.PathSecret != "" || a.HMACSecret != ""
+}
+
+func secretEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func (a *Auth) check(r *http.Request) error {
+	if a.PathSecret != "" {
+		if !secretEqual(strings.TrimPrefix(r.URL.Path, "/"), a.PathSecret) {
+			return errors.New("invalid path")
+		}
+	}
+
+	if a.Bearer != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !secretEqual(token, a.Bearer) {
+			return errors.New("invalid bearer token")
+		}
+	}
+
+	if a.HMACSecret != "" {
+		return a.checkSignature(r)
+	}
+	return nil
+}
+
+// checkSignature verifies the HMAC signature and rejects stale or replayed
+// requests. The body is restored so it can be read again.
+func (a *Auth) checkSignature(r *http.Request) error {
+	ts := r.Header.Get(TimestampHeader)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid " + TimestampHeader)
+	}
+	at := time.Unix(sec, 0)
+	if d := time.Since(at); d > a.MaxSkew || d < -a.MaxSkew {
+		return errors.New("timestamp is too far from now")
+	}
+
+	sig := strings.TrimPrefix(r.Header.Get(SignatureHeader), "sha256=")
+	given, err := hex.DecodeString(sig)
+	if err != nil || len(given) == 0 {
+		return errors.New("missing or invalid " + SignatureHeader)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		return errors.New("cannot read body: " + err.Error())
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	mac := hmac.New(sha256.New, []byte(a.HMACSecret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	if !hmac.Equal(given, mac.Sum(nil)) {
+		return errors.New("signature mismatch")
+	}
+
+	// the decoded MAC, so changing the case of hex digits or the prefix is
+	// still a replay
+	if !a.remember(string(given), at) {
+		return errors.New("replayed request")
+	}
+	return nil
+}
+
+// remember records a MAC and reports whether it is new. MACs are forgotten
+// once their timestamp is out of the accepted window.
+func (a *Auth) remember(mac string, at time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.seen == nil {
+		a.seen = map[string]time.Time{}
+	}
+	now := time.Now()
+	for k, t := range a.seen {
+		if now.Sub(t) > a.MaxSkew {
+			delete(a.seen, k)
+		}
+	}
+
+	if _, ok := a.seen[mac]; ok {
+		return false
+	}
+	a.seen[mac] = at
+	return true
+}
+
+// Wrap returns a handler which responds 401 to unauthenticated requests and
+// passes others to h.
+func (a *Auth) Wrap(h http.Handler) http.Handler {
+	if !a.Enabled() {
+		return h
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.check(r); err != nil {
+			log.Warn().Err(err).
+				Str("remote", r.RemoteAddr).
+				Msg("rejected unauthenticated request")
+			reply(w, http.StatusUnauthorized, Response{
+				Status: StatusRejected,
+				Error:  "unauthorized: " + err.Error(),
+			})
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
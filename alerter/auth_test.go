@@ -0,0 +1,182 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBody = `{"level":"WARNING"}`
+
+// sign returns the signature header of body at ts signed with secret.
+func sign(secret string, ts time.Time, body string) (string, string) {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "." + body))
+	return t, "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type authRequest struct {
+	path      string
+	bearer    string
+	timestamp string
+	signature string
+}
+
+func (r authRequest) do(h http.Handler) *httptest.ResponseRecorder {
+	path := r.path
+	if path == "" {
+		path = "/"
+	}
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(testBody))
+	if r.bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+r.bearer)
+	}
+	if r.timestamp != "" {
+		req.Header.Set(TimestampHeader, r.timestamp)
+	}
+	if r.signature != "" {
+		req.Header.Set(SignatureHeader, r.signature)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// echo responds 200 with the request body, to check the body is restored
+// after the signature is verified.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.Copy(w, r.Body)
+})
+
+func TestAuthWrap(t *testing.T) {
+	now := time.Now()
+	ts, sig := sign("secret", now, testBody)
+	staleTS, staleSig := sign("secret", now.Add(-10*time.Minute), testBody)
+	futureTS, futureSig := sign("secret", now.Add(10*time.Minute), testBody)
+	_, wrongSig := sign("other", now, testBody)
+
+	cases := []struct {
+		name string
+		auth *Auth
+		req  authRequest
+		// expected error in the 401 body, empty if the request passes
+		err string
+	}{
+		{"no auth", &Auth{}, authRequest{}, ""},
+		{"bearer", &Auth{Bearer: "tok"}, authRequest{bearer: "tok"}, ""},
+		{"missing bearer", &Auth{Bearer: "tok"}, authRequest{}, "invalid bearer token"},
+		{"wrong bearer", &Auth{Bearer: "tok"}, authRequest{bearer: "tok2"}, "invalid bearer token"},
+		{"path", &Auth{PathSecret: "p"}, authRequest{path: "/p"}, ""},
+		{"wrong path", &Auth{PathSecret: "p"}, authRequest{path: "/q"}, "invalid path"},
+		{"signature", &Auth{HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{timestamp: ts, signature: sig}, ""},
+		{"signature without prefix", &Auth{HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{timestamp: ts, signature: strings.TrimPrefix(sig, "sha256=")}, ""},
+		{"missing timestamp", &Auth{HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{signature: sig}, "missing or invalid " + TimestampHeader},
+		{"stale timestamp", &Auth{HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{timestamp: staleTS, signature: staleSig}, "timestamp is too far from now"},
+		{"future timestamp", &Auth{HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{timestamp: futureTS, signature: futureSig}, "timestamp is too far from now"},
+		{"missing signature", &Auth{HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{timestamp: ts}, "missing or invalid " + SignatureHeader},
+		{"wrong signature", &Auth{HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{timestamp: ts, signature: wrongSig}, "signature mismatch"},
+		{"signature of other timestamp", &Auth{HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{timestamp: strconv.FormatInt(now.Unix()-1, 10), signature: sig}, "signature mismatch"},
+		{"all methods", &Auth{Bearer: "tok", PathSecret: "p", HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{path: "/p", bearer: "tok", timestamp: ts, signature: sig}, ""},
+		{"all methods with wrong bearer", &Auth{Bearer: "tok", PathSecret: "p", HMACSecret: "secret", MaxSkew: time.Minute},
+			authRequest{path: "/p", bearer: "x", timestamp: ts, signature: sig}, "invalid bearer token"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := c.req.do(c.auth.Wrap(echo))
+			if c.err == "" {
+				if w.Code != http.StatusOK {
+					t.Fatalf("got %d %s, want 200", w.Code, w.Body)
+				}
+				if w.Body.String() != testBody {
+					t.Fatalf("handler got body %q, want %q", w.Body, testBody)
+				}
+				return
+			}
+			checkUnauthorized(t, w, c.err)
+		})
+	}
+}
+
+func checkUnauthorized(t *testing.T, w *httptest.ResponseRecorder, want string) {
+	t.Helper()
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("got %d %s, want 401", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type is %q, want application/json", ct)
+	}
+	var resp Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid body %q: %v", w.Body, err)
+	}
+	if resp.Status != StatusRejected {
+		t.Errorf("status is %q, want %q", resp.Status, StatusRejected)
+	}
+	if resp.Error != "unauthorized: "+want {
+		t.Errorf("error is %q, want %q", resp.Error, "unauthorized: "+want)
+	}
+}
+
+func TestAuthReplay(t *testing.T) {
+	ts, sig := sign("secret", time.Now(), testBody)
+	hexSig := strings.TrimPrefix(sig, "sha256=")
+
+	cases := []struct {
+		name      string
+		signature string
+	}{
+		{"same signature", sig},
+		{"upper case", "sha256=" + strings.ToUpper(hexSig)},
+		{"mixed case", "sha256=" + strings.ToUpper(hexSig[:8]) + hexSig[8:]},
+		{"without prefix", hexSig},
+		{"upper case without prefix", strings.ToUpper(hexSig)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			auth := &Auth{HMACSecret: "secret", MaxSkew: time.Minute}
+			h := auth.Wrap(echo)
+			if w := (authRequest{timestamp: ts, signature: sig}).do(h); w.Code != http.StatusOK {
+				t.Fatalf("first request: got %d %s, want 200", w.Code, w.Body)
+			}
+			w := authRequest{timestamp: ts, signature: c.signature}.do(h)
+			checkUnauthorized(t, w, "replayed request")
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/group"
+	"github.com/raohwork/komodo-tg-alerter/metrics"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// NewGrouper creates a Grouper with rules which queues digests for a and
+// keeps pending batches in a.DB, and sets it as a.Groups.
+func (a *Alerter) NewGrouper(rules []group.Config, tz *time.Location) (*group.Grouper, error) {
+	g, err := group.New(rules, tz, a.DB, a.sendDigest)
+	if err != nil {
+		return nil, err
+	}
+	a.Groups = g
+	return g, nil
+}
+
+// sendDigest renders d and queues it. A digest of a single alert is sent as
+// that alert, so it can be acknowledged and resolved like others. If the
+// digest cannot be rendered, its alerts are sent one by one.
+func (a *Alerter) sendDigest(d *group.Digest) {
+	l := log.With().
+		Str("group", d.Group).
+		Str("notifier", d.Dest.Notifier).
+		Int("alerts", len(d.Alerts)).
+		Logger()
+	ch, ok := a.Channels[d.Dest.Notifier]
+	if !ok {
+		l.Error().Msg("digest dropped, unknown notifier")
+		return
+	}
+
+	if len(d.Alerts) > 1 {
+		text, err := ch.Renderer.RenderDigest(&tmpl.Digest{
+			Group:  d.Group,
+			Labels: d.Labels,
+			Alerts: d.Alerts,
+			Start:  d.Start,
+			End:    time.Now(),
+		})
+		if err == nil {
+			a.push(l, queue.Message{
+				AlertID:  newAlertID(),
+				AlertIDs: d.IDs,
+				Alert:    *d.Alerts[0],
+				Route:    d.Dest.Route,
+				Notifier: d.Dest.Notifier,
+				ChatID:   d.Dest.Chat,
+				ThreadID: d.Dest.Thread,
+				Text:     text,
+				Format:   string(ch.Renderer.DigestFormat()),
+				Digest:   len(d.Alerts),
+			})
+			return
+		}
+		metrics.RenderFailures.WithLabelValues(d.Dest.Notifier, "digest").Inc()
+		l.Error().Err(err).Msg("failed to render digest, sending alerts one by one")
+	}
+
+	for i, alert := range d.Alerts {
+		msg := queue.Message{
+			AlertID:  d.IDs[i],
+			Alert:    *alert,
+			Route:    d.Dest.Route,
+			Notifier: d.Dest.Notifier,
+			ChatID:   d.Dest.Chat,
+			ThreadID: d.Dest.Thread,
+			Format:   string(ch.Renderer.Format(alert.Data.Type)),
+		}
+		var err error
+		msg.Text, msg.Footer, err = render(ch, alert, false)
+		if err != nil {
+			metrics.RenderFailures.WithLabelValues(d.Dest.Notifier, alert.Data.Type).Inc()
+			l.Error().Err(err).Str("alert_id", d.IDs[i]).Msg("failed to render grouped alert")
+			continue
+		}
+		a.push(l, msg)
+	}
+}
+
+// push queues msg of a digest, logging failures with l.
+func (a *Alerter) push(l zerolog.Logger, msg queue.Message) {
+	if _, err := a.Queue.Push(msg); err != nil {
+		l.Error().Err(err).Str("alert_id", msg.AlertID).Msg("failed to queue digest")
+		return
+	}
+	a.recordQueued(&msg)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/heartbeat"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+	"github.com/rs/zerolog/log"
+)
+
+// NewHeartbeat sets a.Heartbeat to a monitor sending its notices to dests.
+func (a *Alerter) NewHeartbeat(interval time.Duration, dests []route.Destination) {
+	a.Heartbeat = heartbeat.New(interval, func(data *tmpl.Heartbeat) {
+		l := log.With().Dur("silent", data.Silent).Logger()
+		if data.Recovered {
+			l.Info().Msg("komodo is heard from again")
+		} else {
+			l.Warn().Time("last_seen", data.LastSeen).Msg("komodo missed the heartbeat deadline")
+		}
+		if err := a.QueueHeartbeat(dests, data); err != nil {
+			l.Error().Err(err).Msg("failed to queue heartbeat notice")
+		}
+	})
+}
+
+// QueueHeartbeat renders a heartbeat notice for each destination and queues
+// it.
+func (a *Alerter) QueueHeartbeat(dests []route.Destination, data *tmpl.Heartbeat) error {
+	msg := queue.Message{Heartbeat: true}
+	return a.queueNotice(dests, "heartbeat", msg, func(r *tmpl.Renderer) (string, tmpl.Format, error) {
+		text, err := r.RenderHeartbeat(data)
+		return text, r.HeartbeatFormat(), err
+	})
+}
+
+// ServeHeartbeat records a beat of a.Heartbeat, for Komodo actions or cron
+// jobs proving Komodo is alive without sending an alert.
+func (a *Alerter) ServeHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if a.Heartbeat == nil {
+		reply(w, http.StatusNotFound, Response{
+			Status: StatusRejected,
+			Error:  "heartbeat is not enabled",
+		})
+		return
+	}
+	a.Heartbeat.Beat()
+	reply(w, http.StatusOK, Response{Status: StatusAlive})
+}
@@ -0,0 +1,187 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"errors"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/history"
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+	"github.com/rs/zerolog/log"
+)
+
+// status returns the status reported for an alert Dispatch returned err for.
+func status(err error) string {
+	switch {
+	case err == nil:
+		return StatusQueued
+	case errors.Is(err, ErrSilenced):
+		return StatusSilenced
+	case errors.Is(err, ErrSuppressed):
+		return StatusSuppressed
+	case errors.Is(err, ErrGrouped):
+		return StatusGrouped
+	case errors.Is(err, route.ErrNoRoute):
+		return StatusDropped
+	case errors.Is(err, tmpl.ErrNoTemplate):
+		return StatusRejected
+	}
+	return StatusFailed
+}
+
+// recordAlert adds an alert to History before it is dispatched.
+func (a *Alerter) recordAlert(id string, data *komodo.AlertInfo) {
+	if a.History == nil {
+		return
+	}
+	err := a.History.Add(&history.Entry{AlertID: id, Alert: *data})
+	if err != nil {
+		log.Warn().Err(err).Str("alert_id", id).Msg("failed to record alert in history")
+	}
+}
+
+// update changes the history entry of id, logging failures.
+func (a *Alerter) update(id string, fn func(*history.Entry)) {
+	if err := a.History.Update(id, fn); err != nil {
+		log.Warn().Err(err).Str("alert_id", id).Msg("failed to update alert history")
+	}
+}
+
+// delivery returns the delivery of e to the chat of msg, adding it if there
+// is none.
+func delivery(e *history.Entry, msg *queue.Message) *history.Delivery {
+	if d := e.Delivery(msg.Notifier, msg.ChatID, msg.ThreadID); d != nil {
+		return d
+	}
+	e.Deliveries = append(e.Deliveries, history.Delivery{
+		Route:    msg.Route,
+		Notifier: msg.Notifier,
+		ChatID:   msg.ChatID,
+		ThreadID: msg.ThreadID,
+	})
+	return &e.Deliveries[len(e.Deliveries)-1]
+}
+
+// recordDispatch records the result of Dispatch: the first queued of msgs
+// are in the delivery queue, and the alert is held for digests of grouped.
+// Deliveries which have been attempted already are kept.
+func (a *Alerter) recordDispatch(id string, msgs []queue.Message, queued int, grouped []route.Delivery, err error) {
+	if a.History == nil {
+		return
+	}
+	a.update(id, func(e *history.Entry) {
+		e.Status = status(err)
+		if err != nil {
+			e.Error = err.Error()
+		}
+		for i := range msgs {
+			msg := &msgs[i]
+			if e.Delivery(msg.Notifier, msg.ChatID, msg.ThreadID) != nil {
+				continue
+			}
+			d := delivery(e, msg)
+			d.Text = msg.Text
+			d.Format = msg.Format
+			d.Status = history.DeliveryPending
+			if i >= queued {
+				d.Status = history.DeliveryFailed
+				d.Error = "not queued"
+			}
+		}
+		for _, g := range grouped {
+			if e.Delivery(g.Notifier, g.Chat, g.Thread) != nil {
+				continue
+			}
+			e.Deliveries = append(e.Deliveries, history.Delivery{
+				Route:    g.Route,
+				Notifier: g.Notifier,
+				ChatID:   g.Chat,
+				ThreadID: g.Thread,
+				Status:   history.DeliveryGrouped,
+			})
+		}
+	})
+}
+
+// alertIDs returns the IDs of alerts msg is about.
+func alertIDs(msg *queue.Message) []string {
+	if msg.Digest > 0 {
+		return msg.AlertIDs
+	}
+	return []string{msg.AlertID}
+}
+
+// recordQueued records that msg of a digest, or an alert of it sent alone,
+// is in the delivery queue.
+func (a *Alerter) recordQueued(msg *queue.Message) {
+	if a.History == nil {
+		return
+	}
+	for _, id := range alertIDs(msg) {
+		a.update(id, func(e *history.Entry) {
+			d := delivery(e, msg)
+			d.Text = msg.Text
+			d.Format = msg.Format
+			d.Status = history.DeliveryPending
+			if msg.Digest > 0 {
+				d.Digest = msg.AlertID
+			}
+		})
+	}
+}
+
+// recordDelivery records the result of a delivery attempt of job. Messages
+// not about received alerts, like reports, are not recorded.
+func (a *Alerter) recordDelivery(job *queue.Job, ref string, err error) {
+	msg := &job.Message
+	if a.History == nil || msg.Report || msg.Heartbeat {
+		return
+	}
+	now := time.Now()
+	for _, id := range alertIDs(msg) {
+		a.update(id, func(e *history.Entry) {
+			d := delivery(e, msg)
+			d.Text = msg.Text
+			d.Format = msg.Format
+			if msg.Digest > 0 {
+				d.Digest = msg.AlertID
+			}
+			// the queue counts the attempt after it returns
+			d.Attempts = job.Attempts + 1
+			d.At = now
+			d.Error = ""
+			switch {
+			case err == nil:
+				d.Status = history.DeliverySent
+				if ref != "" {
+					d.Ref = ref
+				}
+			case queue.IsPermanent(err):
+				d.Status = history.DeliveryFailed
+				d.Error = err.Error()
+			default:
+				d.Status = history.DeliveryRetrying
+				d.Error = err.Error()
+			}
+		})
+	}
+}
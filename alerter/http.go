@@ -0,0 +1,207 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/metrics"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+	"github.com/rs/zerolog/log"
+)
+
+// Values of Response.Status.
+const (
+	StatusSent       = "sent"       // delivered to Telegram
+	StatusQueued     = "queued"     // waiting in the delivery queue
+	StatusRejected   = "rejected"   // request is invalid, nothing will be sent
+	StatusFailed     = "failed"     // accepted but cannot be delivered
+	StatusDropped    = "dropped"    // accepted but there is nowhere to send it
+	StatusSuppressed = "suppressed" // duplicated or flapping, not sent
+	StatusSilenced   = "silenced"   // muted by a silence, not sent
+	StatusGrouped    = "grouped"    // waiting to be sent in a digest
+	StatusAlive      = "alive"      // heartbeat recorded
+)
+
+// Response is the JSON body returned by the webhook endpoint.
+type Response struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	AlertID string `json:"alert_id,omitempty"`
+}
+
+func reply(w http.ResponseWriter, code int, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ServeHTTP handles webhook requests from Komodo.
+//
+//   - 202 the alert is queued, or waiting for a digest
+//   - 200 the alert is delivered (only if Wait is set), suppressed,
+//     silenced, or no route matched
+//   - 400 the body is not a valid alert
+//   - 405 the method is not POST
+//   - 422 there is no template for the alert type
+//   - 500 the template failed to render
+//   - 502 Telegram rejected the message for some chat, it will not be retried
+//   - 503 the alert cannot be queued, in which case nothing is queued and
+//     Komodo may retry, or the first delivery attempt failed and will be
+//     retried
+func (a *Alerter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		reply(w, http.StatusMethodNotAllowed, Response{
+			Status: StatusRejected,
+			Error:  "method not allowed",
+		})
+		return
+	}
+
+	var data komodo.AlertInfo
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err == nil {
+		err = json.Unmarshal(body, &data)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to decode request body")
+		reply(w, http.StatusBadRequest, Response{
+			Status: StatusRejected,
+			Error:  "invalid alert: " + err.Error(),
+		})
+		return
+	}
+
+	metrics.Received.WithLabelValues(data.Data.Type, data.Level).Inc()
+	if a.Archive != nil {
+		if err := a.Archive.Write(body); err != nil {
+			log.Warn().Err(err).Msg("failed to archive request body")
+		}
+	}
+	if a.Heartbeat != nil {
+		a.Heartbeat.Beat()
+	}
+	if _, err := data.Data.Decode(); err != nil {
+		// templates still get the fields which can be decoded
+		log.Warn().Err(err).Str("type", data.Data.Type).Msg("unexpected alert payload")
+	}
+	id, jobs, err := a.Dispatch(&data)
+	l := log.With().Str("alert_id", id).Str("type", data.Data.Type).Logger()
+	switch {
+	case errors.Is(err, ErrSilenced):
+		l.Info().Err(err).
+			Str("level", data.Level).
+			Str("target", data.Target.ID).
+			Msg("alert silenced")
+		reply(w, http.StatusOK, Response{
+			Status:  StatusSilenced,
+			Error:   err.Error(),
+			AlertID: id,
+		})
+		return
+	case errors.Is(err, ErrSuppressed):
+		l.Info().Err(err).Msg("alert suppressed")
+		reply(w, http.StatusOK, Response{
+			Status:  StatusSuppressed,
+			Error:   err.Error(),
+			AlertID: id,
+		})
+		return
+	case errors.Is(err, ErrGrouped):
+		l.Info().Msg("alert waiting for digest")
+		reply(w, http.StatusAccepted, Response{Status: StatusGrouped, AlertID: id})
+		return
+	case errors.Is(err, route.ErrNoRoute):
+		l.Warn().Msg("no route matched, alert dropped")
+		reply(w, http.StatusOK, Response{
+			Status:  StatusDropped,
+			Error:   err.Error(),
+			AlertID: id,
+		})
+		return
+	case errors.Is(err, tmpl.ErrNoTemplate):
+		l.Error().Err(err).Msg("no template for alert")
+		reply(w, http.StatusUnprocessableEntity, Response{
+			Status:  StatusRejected,
+			Error:   err.Error(),
+			AlertID: id,
+		})
+		return
+	case err != nil:
+		code := http.StatusServiceUnavailable
+		if errors.Is(err, ErrRender) {
+			code = http.StatusInternalServerError
+		}
+		l.Error().Err(err).Msg("failed to dispatch alert")
+		reply(w, code, Response{
+			Status:  StatusFailed,
+			Error:   err.Error(),
+			AlertID: id,
+		})
+		return
+	}
+
+	l.Debug().Int("jobs", len(jobs)).Msg("alert queued")
+	if a.Wait <= 0 {
+		reply(w, http.StatusAccepted, Response{Status: StatusQueued, AlertID: id})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), a.Wait)
+	defer cancel()
+	var pending bool
+	var failed, rejected error
+	for _, job := range jobs {
+		attempted, err := a.Queue.Wait(ctx, job)
+		switch {
+		case !attempted:
+			pending = true
+		case queue.IsPermanent(err):
+			rejected = err
+		case err != nil:
+			failed = err
+		}
+	}
+
+	switch {
+	case rejected != nil:
+		reply(w, http.StatusBadGateway, Response{
+			Status:  StatusFailed,
+			Error:   rejected.Error(),
+			AlertID: id,
+		})
+	case failed != nil:
+		reply(w, http.StatusServiceUnavailable, Response{
+			Status:  StatusQueued,
+			Error:   failed.Error(),
+			AlertID: id,
+		})
+	case pending:
+		reply(w, http.StatusAccepted, Response{Status: StatusQueued, AlertID: id})
+	default:
+		reply(w, http.StatusOK, Response{Status: StatusSent, AlertID: id})
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/raohwork/komodo-tg-alerter/metrics"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+)
+
+// queueNotice renders a message which is not about a single alert for each
+// destination with render, and queues it as msg with the destination filled
+// in. kind labels render failures in metrics.
+func (a *Alerter) queueNotice(dests []route.Destination, kind string, msg queue.Message, render func(*tmpl.Renderer) (string, tmpl.Format, error)) error {
+	var errs []error
+	for _, d := range dests {
+		ch, ok := a.Channels[d.Notifier]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown notifier %s", d.Notifier))
+			continue
+		}
+		text, format, err := render(ch.Renderer)
+		if err != nil {
+			metrics.RenderFailures.WithLabelValues(d.Notifier, kind).Inc()
+			errs = append(errs, fmt.Errorf("%s: %w", d.Notifier, err))
+			continue
+		}
+		msg.AlertID = newAlertID()
+		msg.Notifier = d.Notifier
+		msg.ChatID = d.Chat
+		msg.ThreadID = d.Thread
+		msg.Text = text
+		msg.Format = string(format)
+		if _, err = a.Queue.Push(msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", d.Notifier, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// QueueReport renders a scheduled report for each destination and queues it.
+func (a *Alerter) QueueReport(dests []route.Destination, data *tmpl.Report) error {
+	msg := queue.Message{Route: data.Name, Report: true}
+	return a.queueNotice(dests, "report", msg, func(r *tmpl.Renderer) (string, tmpl.Format, error) {
+		text, err := r.RenderReport(data)
+		return text, r.ReportFormat(), err
+	})
+}
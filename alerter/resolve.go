@@ -0,0 +1,233 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package alerter
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/notify"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/rs/zerolog/log"
+)
+
+const openBucket = "open"
+
+// How resolved alerts are delivered.
+const (
+	ResolveEdit  = "edit"  // append the footer to the original message
+	ResolveReply = "reply" // reply to the original message with the footer
+	ResolveSend  = "send"  // send a new message
+)
+
+// SentMessage is a message sent for an alert.
+type SentMessage struct {
+	Notifier string `json:"notifier"`
+	ChatID   int64  `json:"chat_id,omitempty"`
+	ThreadID int    `json:"thread_id,omitempty"`
+	// message ID returned by the notifier
+	Ref  string `json:"ref"`
+	Text string `json:"text"`
+	// markup of Text, see tmpl.Format
+	Format string `json:"format,omitempty"`
+}
+
+// OpenAlert is an alert which has been delivered but not resolved yet.
+type OpenAlert struct {
+	Key     string             `json:"key"`
+	AlertID string             `json:"alert_id"`
+	Level   string             `json:"level"`
+	Type    string             `json:"type"`
+	Target  komodo.AlertTarget `json:"target"`
+	// name of the target if known
+	Name     string        `json:"name,omitempty"`
+	IssuedAt time.Time     `json:"issued_at"`
+	Messages []SentMessage `json:"messages"`
+	// who acknowledged the alert, see Ack
+	AckedBy string    `json:"acked_by,omitempty"`
+	AckedAt time.Time `json:"acked_at,omitzero"`
+}
+
+// OpenAlerts returns all alerts which are not resolved yet, oldest first.
+func (a *Alerter) OpenAlerts() ([]OpenAlert, error) {
+	var ret []OpenAlert
+	err := a.DB.ForEach(openBucket, func(_ string, val []byte) error {
+		var o OpenAlert
+		if err := json.Unmarshal(val, &o); err != nil {
+			return err
+		}
+		ret = append(ret, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].IssuedAt.Before(ret[j].IssuedAt)
+	})
+	return ret, nil
+}
+
+// track remembers the message sent for an open alert.
+func (a *Alerter) track(msg *queue.Message, ref string) {
+	a.openMu.Lock()
+	defer a.openMu.Unlock()
+
+	alert := &msg.Alert
+	key := alert.Key()
+	var o OpenAlert
+	if _, err := a.DB.Get(openBucket, key, &o); err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("replacing corrupted open alert")
+	}
+
+	o.Key = key
+	o.AlertID = msg.AlertID
+	o.Level = alert.Level
+	o.Type = alert.Data.Type
+	o.Target = alert.Target
+	o.Name = alert.Data.Payload.Get("name").Str()
+	if o.IssuedAt.IsZero() {
+		o.IssuedAt = alert.IssuedAt()
+	}
+
+	m := SentMessage{
+		Notifier: msg.Notifier,
+		ChatID:   msg.ChatID,
+		ThreadID: msg.ThreadID,
+		Ref:      ref,
+		Text:     msg.Text,
+		Format:   msg.Format,
+	}
+	replaced := false
+	for i := range o.Messages {
+		if o.Messages[i].sameChat(msg) {
+			o.Messages[i] = m
+			replaced = true
+		}
+	}
+	if !replaced {
+		o.Messages = append(o.Messages, m)
+	}
+
+	if err := a.DB.Put(openBucket, key, &o); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to save open alert")
+	}
+}
+
+func (m *SentMessage) sameChat(msg *queue.Message) bool {
+	return m.Notifier == msg.Notifier &&
+		m.ChatID == msg.ChatID &&
+		m.ThreadID == msg.ThreadID
+}
+
+// lookup finds the message sent to the chat of msg for the alert of msg.
+func (a *Alerter) lookup(msg *queue.Message) *SentMessage {
+	a.openMu.Lock()
+	defer a.openMu.Unlock()
+
+	var o OpenAlert
+	if ok, err := a.DB.Get(openBucket, msg.Alert.Key(), &o); !ok || err != nil {
+		return nil
+	}
+	for _, m := range o.Messages {
+		if m.sameChat(msg) {
+			return &m
+		}
+	}
+	return nil
+}
+
+// untrack forgets the message sent to the chat of msg for the alert of msg.
+func (a *Alerter) untrack(msg *queue.Message) {
+	a.openMu.Lock()
+	defer a.openMu.Unlock()
+
+	key := msg.Alert.Key()
+	var o OpenAlert
+	if ok, err := a.DB.Get(openBucket, key, &o); !ok || err != nil {
+		return
+	}
+
+	msgs := o.Messages[:0]
+	for _, m := range o.Messages {
+		if !m.sameChat(msg) {
+			msgs = append(msgs, m)
+		}
+	}
+	o.Messages = msgs
+
+	var err error
+	if len(o.Messages) == 0 {
+		err = a.DB.Delete(openBucket, key)
+	} else {
+		err = a.DB.Put(openBucket, key, &o)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to update open alert")
+	}
+}
+
+// deliverResolved updates the message of the original alert according to
+// ResolveMode, or sends a new one if there is no such message. If n cannot
+// edit messages it replies instead, and if it cannot reply either it sends a
+// new message. It returns the ID of the message edited or sent.
+func (a *Alerter) deliverResolved(ctx context.Context, n notify.Notifier, msg *queue.Message) (string, error) {
+	orig := a.lookup(msg)
+	mode := a.ResolveMode
+	if orig == nil || orig.Ref == "" {
+		mode = ResolveSend
+	}
+	editor, canEdit := n.(notify.Editor)
+	if mode == ResolveEdit && !canEdit {
+		mode = ResolveReply
+	}
+	replier, canReply := n.(notify.Replier)
+	if mode == ResolveReply && !canReply {
+		mode = ResolveSend
+	}
+
+	var ref string
+	var err error
+	switch mode {
+	case ResolveEdit:
+		text := strings.TrimRight(orig.Text, "\n") + "\n\n" + msg.Footer
+		ref = orig.Ref
+		err = editor.Edit(ctx, msg, orig.Ref, text)
+		if queue.IsPermanent(err) {
+			// the original message is deleted or cannot be edited anymore
+			log.Warn().Err(err).
+				Str("alert_id", msg.AlertID).
+				Msg("cannot edit original message, sending a new one")
+			ref, err = n.Send(ctx, msg)
+		}
+	case ResolveReply:
+		ref, err = replier.Reply(ctx, msg, orig.Ref, msg.Footer)
+	default:
+		ref, err = n.Send(ctx, msg)
+	}
+
+	if err == nil && orig != nil {
+		a.untrack(msg)
+	}
+	return ref, err
+}
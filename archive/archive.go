@@ -0,0 +1,150 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package archive keeps raw webhook bodies received from Komodo, so alerts
+// can be replayed later.
+//
+// Bodies are appended as JSON lines to one file per day, named like
+// alerts-2026-01-06.jsonl. Files are never removed by kta.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+)
+
+type Archive struct {
+	dir string
+	tz  *time.Location
+
+	mu sync.Mutex
+}
+
+// New creates an Archive writing files in dir, which is created if missing.
+// tz decides when a day starts.
+func New(dir string, tz *time.Location) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create archive directory: %w", err)
+	}
+	return &Archive{dir: dir, tz: tz}, nil
+}
+
+// FileName returns the name of the file bodies received at t are written to.
+func FileName(t time.Time) string {
+	return "alerts-" + t.Format(time.DateOnly) + ".jsonl"
+}
+
+// Write appends body, which must be valid JSON, to the file of today.
+func (a *Archive) Write(body []byte) error {
+	var line bytes.Buffer
+	if err := json.Compact(&line, body); err != nil {
+		return err
+	}
+	line.WriteByte('\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	name := filepath.Join(a.dir, FileName(time.Now().In(a.tz)))
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(line.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads alerts from path. A .json file holds an alert or an array of
+// them, other files hold an alert per line. If path is a directory, its
+// .json and .jsonl files are read in the order of their names.
+func Load(path string) ([]komodo.AlertInfo, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadFile(path)
+	}
+
+	dir, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var ret []komodo.AlertInfo
+	for _, e := range dir {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".jsonl") {
+			continue
+		}
+		alerts, err := loadFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, alerts...)
+	}
+	return ret, nil
+}
+
+func loadFile(name string) ([]komodo.AlertInfo, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Ext(name) == ".json" {
+		data = bytes.TrimSpace(data)
+		if bytes.HasPrefix(data, []byte("[")) {
+			var ret []komodo.AlertInfo
+			if err := json.Unmarshal(data, &ret); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			return ret, nil
+		}
+		var a komodo.AlertInfo
+		if err := json.Unmarshal(data, &a); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return []komodo.AlertInfo{a}, nil
+	}
+
+	var ret []komodo.AlertInfo
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, len(data)+1)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+		var a komodo.AlertInfo
+		if err := json.Unmarshal([]byte(line), &a); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+		ret = append(ret, a)
+	}
+	return ret, s.Err()
+}
@@ -0,0 +1,117 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package botcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/raohwork/komodo-tg-alerter/alerter"
+)
+
+// status reports uptime, queue depth and counts of open alerts and silences.
+func (c *Commands) status(ctx context.Context, b *bot.Bot, msg *models.Message, args []string) string {
+	var buf strings.Builder
+	buf.WriteString("kta is running\n")
+	if !c.Started.IsZero() {
+		fmt.Fprintf(&buf, "Uptime: %s\n", time.Since(c.Started).Round(time.Second))
+	}
+	fmt.Fprintf(&buf, "Queue: %d pending\n", c.Alerter.Queue.Len())
+
+	if open, err := c.Alerter.OpenAlerts(); err != nil {
+		fmt.Fprintf(&buf, "Open alerts: unknown (%s)\n", err)
+	} else {
+		fmt.Fprintf(&buf, "Open alerts: %d\n", len(open))
+	}
+
+	now := time.Now()
+	active := 0
+	for _, s := range c.Silences.List() {
+		if s.Active(now) {
+			active++
+		}
+	}
+	fmt.Fprintf(&buf, "Silences: %d active\n", active)
+
+	names := make([]string, 0, len(c.Alerter.Channels))
+	for name := range c.Alerter.Channels {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	fmt.Fprintf(&buf, "Notifiers: %s", strings.Join(names, ", "))
+	return buf.String()
+}
+
+// open lists unresolved alerts.
+func (c *Commands) open(ctx context.Context, b *bot.Bot, msg *models.Message, args []string) string {
+	list, err := c.Alerter.OpenAlerts()
+	if err != nil {
+		return "cannot list open alerts: " + err.Error()
+	}
+	if len(list) == 0 {
+		return "No open alerts."
+	}
+
+	var buf strings.Builder
+	for _, o := range list {
+		fmt.Fprintf(&buf, "%s [%s] %s of %s %s since %s",
+			o.AlertID, o.Level, o.Type, o.Target.Type, targetName(&o), c.timefmt(o.IssuedAt))
+		if o.AckedBy != "" {
+			fmt.Fprintf(&buf, ", acked by %s", o.AckedBy)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// ack acknowledges an open alert.
+func (c *Commands) ack(ctx context.Context, b *bot.Bot, msg *models.Message, args []string) string {
+	if !c.allowed(msg) {
+		return errNotAllowed
+	}
+	if len(args) != 1 {
+		return "usage: /ack id, see /open for IDs"
+	}
+
+	o, err := c.Alerter.Ack(args[0], sender(msg))
+	if errors.Is(err, alerter.ErrNotOpen) {
+		return "No open alert " + args[0] + ", see /open for IDs"
+	}
+	if errors.Is(err, alerter.ErrAcked) {
+		return "Already acknowledged by " + o.AckedBy
+	}
+	if err != nil {
+		return "cannot acknowledge alert: " + err.Error()
+	}
+	c.Alerter.AnnounceAck(ctx, o)
+	return fmt.Sprintf("%s of %s %s acknowledged by %s, muted until resolved",
+		o.Type, o.Target.Type, targetName(o), o.AckedBy)
+}
+
+func targetName(o *alerter.OpenAlert) string {
+	if o.Name != "" {
+		return o.Name
+	}
+	return o.Target.ID
+}
@@ -0,0 +1,182 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package botcmd handles commands sent to the Telegram bot.
+package botcmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/raohwork/komodo-tg-alerter/alerter"
+	"github.com/raohwork/komodo-tg-alerter/silence"
+	"github.com/rs/zerolog/log"
+)
+
+// Commands handles bot commands sent in the chats kta delivers alerts to.
+//
+// Everyone in those chats can run read-only commands. Commands changing
+// anything (creating silences, acknowledging alerts) are only allowed for
+// users in Admins, who can also talk to the bot in private chats.
+type Commands struct {
+	Alerter  *alerter.Alerter
+	Silences *silence.Manager
+	// chats commands are accepted from, other chats are ignored
+	Chats map[int64]bool
+	// Telegram user IDs allowed to run mutating commands
+	Admins map[int64]bool
+	// timezone of times in replies
+	TZ *time.Location
+	// when kta started, for /status
+	Started time.Time
+}
+
+// errNotAllowed is the reply to mutating commands from users not in Admins.
+const errNotAllowed = "You are not allowed to do this."
+
+// allowed reports whether the sender of msg may run mutating commands.
+func (c *Commands) allowed(msg *models.Message) bool {
+	return msg.From != nil && c.Admins[msg.From.ID]
+}
+
+func (c *Commands) timefmt(t time.Time) string {
+	if c.TZ != nil {
+		t = t.In(c.TZ)
+	}
+	return t.Format(time.DateTime)
+}
+
+// parseCommand splits "/cmd@bot arg1 arg2" into "cmd" and its arguments.
+func parseCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return "", nil
+	}
+	name, _, _ := strings.Cut(fields[0][1:], "@")
+	return strings.ToLower(name), fields[1:]
+}
+
+type handler func(ctx context.Context, b *bot.Bot, msg *models.Message, args []string) string
+
+type command struct {
+	name  string
+	usage string
+	h     handler
+}
+
+func (c *Commands) commands() []command {
+	return []command{
+		{"status", "show kta status and queue depth", c.status},
+		{"open", "list unresolved alerts", c.open},
+		{"ack", "<id> acknowledge an alert, muting it until resolved", c.ack},
+		{"silence", "[key=pattern... duration [comment]] list or create silences", c.silence},
+		{"unsilence", "<id> remove a silence", c.unsilence},
+		{"help", "show this message", c.help},
+	}
+}
+
+// Register adds command handlers to b.
+func (c *Commands) Register(b *bot.Bot) {
+	for _, cmd := range c.commands() {
+		c.handle(b, cmd.name, cmd.h)
+	}
+}
+
+// SetMyCommands publishes the command list shown by Telegram clients.
+func (c *Commands) SetMyCommands(ctx context.Context, b *bot.Bot) error {
+	var list []models.BotCommand
+	for _, cmd := range c.commands() {
+		list = append(list, models.BotCommand{Command: cmd.name, Description: cmd.usage})
+	}
+	_, err := b.SetMyCommands(ctx, &bot.SetMyCommandsParams{Commands: list})
+	return err
+}
+
+func (c *Commands) help(ctx context.Context, b *bot.Bot, msg *models.Message, args []string) string {
+	var buf strings.Builder
+	for _, cmd := range c.commands() {
+		fmt.Fprintf(&buf, "/%s %s\n", cmd.name, cmd.usage)
+	}
+	if !c.allowed(msg) {
+		buf.WriteString("\n/ack, /unsilence and creating silences are limited to admins.")
+	}
+	return buf.String()
+}
+
+func (c *Commands) handle(b *bot.Bot, name string, h handler) {
+	match := func(update *models.Update) bool {
+		if update.Message == nil {
+			return false
+		}
+		cmd, _ := parseCommand(update.Message.Text)
+		return cmd == name
+	}
+
+	b.RegisterHandlerMatchFunc(match, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		msg := update.Message
+		l := log.With().
+			Str("command", name).
+			Int64("chat", msg.Chat.ID).
+			Str("user", sender(msg)).
+			Logger()
+		private := msg.Chat.Type == models.ChatTypePrivate && c.allowed(msg)
+		if !c.Chats[msg.Chat.ID] && !private {
+			l.Warn().Msg("ignoring command from unknown chat")
+			return
+		}
+
+		_, args := parseCommand(msg.Text)
+		l.Info().Strs("args", args).Msg("received command")
+		text := h(ctx, b, msg, args)
+		if text == "" {
+			return
+		}
+		_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          msg.Chat.ID,
+			MessageThreadID: msg.MessageThreadID,
+			Text:            text,
+			ReplyParameters: &models.ReplyParameters{
+				MessageID:                msg.ID,
+				AllowSendingWithoutReply: true,
+			},
+		})
+		if err != nil {
+			l.Error().Err(err).Msg("failed to reply to command")
+		}
+	})
+}
+
+// sender identifies the user who sent msg.
+func sender(msg *models.Message) string {
+	if msg.From == nil {
+		return ""
+	}
+	return userName(msg.From)
+}
+
+// userName identifies a Telegram user by username, or by ID if there is none.
+func userName(u *models.User) string {
+	if u.Username != "" {
+		return "@" + u.Username
+	}
+	return "tg:" + strconv.FormatInt(u.ID, 10)
+}
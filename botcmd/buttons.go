@@ -0,0 +1,124 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package botcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/raohwork/komodo-tg-alerter/alerter"
+	"github.com/raohwork/komodo-tg-alerter/notify"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/silence"
+	"github.com/rs/zerolog/log"
+)
+
+// ButtonSilenceDuration is how long the silence button mutes an alert.
+const ButtonSilenceDuration = time.Hour
+
+// callback handles a button pressed on an alert. hash is the KeyHash of the
+// alert; the returned text is shown to the user as a notification.
+type callback func(ctx context.Context, b *bot.Bot, cq *models.CallbackQuery, hash string) string
+
+// RegisterButtons adds handlers of the buttons attached to alerts by
+// notify.Telegram.
+func (c *Commands) RegisterButtons(b *bot.Bot) {
+	c.handleButton(b, notify.CallbackAck, c.ackButton)
+	c.handleButton(b, notify.CallbackSilence, c.silenceButton)
+}
+
+func (c *Commands) handleButton(b *bot.Bot, prefix string, h callback) {
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, prefix, bot.MatchTypePrefix, func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		cq := update.CallbackQuery
+		l := log.With().
+			Str("button", strings.TrimSuffix(prefix, ":")).
+			Str("user", userName(&cq.From)).
+			Logger()
+
+		var text string
+		switch {
+		case cq.Message.Message == nil || !c.Chats[cq.Message.Message.Chat.ID]:
+			l.Warn().Msg("ignoring button from unknown chat")
+			text = errNotAllowed
+		case !c.Admins[cq.From.ID]:
+			l.Warn().Msg("button pressed by user not in admins")
+			text = errNotAllowed
+		default:
+			l.Info().Str("data", cq.Data).Msg("button pressed")
+			text = h(ctx, b, cq, strings.TrimPrefix(cq.Data, prefix))
+		}
+
+		_, err := b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: cq.ID,
+			Text:            text,
+		})
+		if err != nil {
+			l.Error().Err(err).Msg("failed to answer button")
+		}
+	})
+}
+
+// ackButton acknowledges the alert and shows who did it in its messages.
+func (c *Commands) ackButton(ctx context.Context, b *bot.Bot, cq *models.CallbackQuery, hash string) string {
+	o, err := c.Alerter.Ack(hash, userName(&cq.From))
+	if errors.Is(err, alerter.ErrNotOpen) {
+		return "This alert is not open anymore."
+	}
+	if errors.Is(err, alerter.ErrAcked) {
+		return "Already acknowledged by " + o.AckedBy
+	}
+	if err != nil {
+		return "cannot acknowledge alert: " + err.Error()
+	}
+	c.Alerter.AnnounceAck(ctx, o)
+	return "Acknowledged by " + o.AckedBy
+}
+
+// silenceButton mutes alerts about the same condition for
+// ButtonSilenceDuration.
+func (c *Commands) silenceButton(ctx context.Context, b *bot.Bot, cq *models.CallbackQuery, hash string) string {
+	o, err := c.Alerter.FindOpen(hash)
+	if errors.Is(err, alerter.ErrNotOpen) {
+		return "This alert is not open anymore."
+	}
+	if err != nil {
+		return "cannot find alert: " + err.Error()
+	}
+
+	now := time.Now()
+	s, err := c.Silences.Add(silence.Silence{
+		Match: route.Match{
+			Type:       []string{o.Type},
+			TargetType: []string{o.Target.Type},
+			TargetID:   []string{o.Target.ID},
+		},
+		Start:     now,
+		End:       now.Add(ButtonSilenceDuration),
+		Comment:   fmt.Sprintf("silenced from alert %s", o.AlertID),
+		CreatedBy: userName(&cq.From),
+	})
+	if err != nil {
+		return "cannot create silence: " + err.Error()
+	}
+	return fmt.Sprintf("Silenced until %s (id %s)", c.timefmt(s.End), s.ID)
+}
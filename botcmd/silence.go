@@ -0,0 +1,113 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package botcmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/silence"
+)
+
+const silenceUsage = "usage: /silence key=pattern... duration [comment]\n" +
+	"e.g. /silence type=ServerCpu server_name=prod-* 2h upgrading"
+
+// formatSilences lists silences in plain text.
+func (c *Commands) formatSilences(list []silence.Silence) string {
+	if len(list) == 0 {
+		return "No silences."
+	}
+	var b strings.Builder
+	now := time.Now()
+	for _, s := range list {
+		state := "active"
+		if !s.Active(now) {
+			state = "starts " + c.timefmt(s.Start)
+		}
+		until := "forever"
+		if !s.End.IsZero() {
+			until = "until " + c.timefmt(s.End)
+		}
+		fmt.Fprintf(&b, "%s: %s (%s, %s, %d muted)", s.ID, s.Match.String(), state, until, s.Hits)
+		if s.Comment != "" {
+			fmt.Fprintf(&b, " - %s", s.Comment)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// silence lists silences without arguments, or creates one.
+func (c *Commands) silence(ctx context.Context, b *bot.Bot, msg *models.Message, args []string) string {
+	if len(args) == 0 {
+		return c.formatSilences(c.Silences.List())
+	}
+	if !c.allowed(msg) {
+		return errNotAllowed
+	}
+
+	var matchers []string
+	for len(args) > 0 && strings.Contains(args[0], "=") {
+		matchers = append(matchers, args[0])
+		args = args[1:]
+	}
+	if len(matchers) == 0 || len(args) == 0 {
+		return silenceUsage
+	}
+	match, err := route.ParseMatch(matchers)
+	if err != nil {
+		return err.Error()
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d <= 0 {
+		return "invalid duration " + args[0] + "\n" + silenceUsage
+	}
+
+	now := time.Now()
+	s, err := c.Silences.Add(silence.Silence{
+		Match:     match,
+		Start:     now,
+		End:       now.Add(d),
+		Comment:   strings.Join(args[1:], " "),
+		CreatedBy: sender(msg),
+	})
+	if err != nil {
+		return "cannot create silence: " + err.Error()
+	}
+	return fmt.Sprintf("Silenced %s until %s (id %s)",
+		s.Match.String(), c.timefmt(s.End), s.ID)
+}
+
+// unsilence removes a silence by ID.
+func (c *Commands) unsilence(ctx context.Context, b *bot.Bot, msg *models.Message, args []string) string {
+	if !c.allowed(msg) {
+		return errNotAllowed
+	}
+	if len(args) != 1 {
+		return "usage: /unsilence id"
+	}
+	if err := c.Silences.Remove(args[0]); err != nil {
+		return "cannot remove silence: " + err.Error()
+	}
+	return "Silence " + args[0] + " removed"
+}
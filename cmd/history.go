@@ -0,0 +1,240 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/config"
+	"github.com/raohwork/komodo-tg-alerter/history"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// historyCmd lists alerts recorded by a running server
+var historyCmd = &cobra.Command{
+	Use:   "history [alert-id]",
+	Short: "List alerts received by the running server",
+	Long: `List alerts received by the running server, with what happened to them.
+
+The server is queried through the admin API, so web.admin.token must be set.
+Given an alert ID, the alert and its deliveries are printed in JSON.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.NewConfig()
+		if cfg.AdminToken == "" {
+			return errors.New("web.admin.token is not set, the admin API is disabled")
+		}
+
+		if len(args) == 1 {
+			var e history.Entry
+			u := historyFlags.url(cfg) + "/api/alerts/" + url.PathEscape(args[0])
+			if err := adminGet(cfg, u, &e); err != nil {
+				return err
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(e)
+		}
+
+		entries, err := historyFlags.fetch(cfg)
+		if err != nil {
+			return err
+		}
+		if historyFlags.json {
+			return json.NewEncoder(os.Stdout).Encode(entries)
+		}
+		printHistory(entries, cfg.Timezone())
+		return nil
+	},
+}
+
+var historyFlags struct {
+	alertQuery
+	json bool
+}
+
+// alertQuery selects alerts from the history of the running server with
+// flags, see history.ParseQuery.
+type alertQuery struct {
+	server   string
+	typ      string
+	level    string
+	status   string
+	since    string
+	until    string
+	resolved string
+	limit    int
+}
+
+func (q *alertQuery) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&q.server, "server", "", "URL of the admin API (default from web.admin.bind or web.bind)")
+	f.StringVar(&q.typ, "type", "", "only alerts of this type")
+	// shadows the logging level of the root command, which is still set by
+	// log.level or KTA_LOG_LEVEL
+	f.StringVar(&q.level, "level", "", "only alerts of this level, like WARNING or CRITICAL")
+	f.StringVar(&q.status, "status", "", "only alerts with this status when received, like failed or rejected")
+	f.StringVar(&q.since, "since", "", "only alerts received since, RFC3339 or a duration before now like 24h")
+	f.StringVar(&q.until, "until", "", "only alerts received before, RFC3339 or a duration before now")
+	f.StringVar(&q.resolved, "resolved", "", "only resolved (true) or unresolved (false) alerts")
+	f.IntVarP(&q.limit, "limit", "n", history.DefaultLimit, "at most this many latest alerts, 0 for all")
+}
+
+// url returns the base URL of the admin API.
+func (q *alertQuery) url(cfg *config.Config) string {
+	if q.server != "" {
+		return strings.TrimRight(q.server, "/")
+	}
+	return adminURL(cfg)
+}
+
+// fetch returns the selected alerts, oldest first.
+func (q *alertQuery) fetch(cfg *config.Config) ([]history.Entry, error) {
+	if cfg.AdminToken == "" {
+		return nil, errors.New("web.admin.token is not set, the admin API is disabled")
+	}
+	v := url.Values{}
+	for k, s := range map[string]string{
+		"type":     q.typ,
+		"level":    q.level,
+		"status":   q.status,
+		"since":    q.since,
+		"until":    q.until,
+		"resolved": q.resolved,
+		"limit":    strconv.Itoa(q.limit),
+	} {
+		if s != "" {
+			v.Set(k, s)
+		}
+	}
+	var ret []history.Entry
+	err := adminGet(cfg, q.url(cfg)+"/api/alerts?"+v.Encode(), &ret)
+	return ret, err
+}
+
+// adminURL returns the base URL of the admin API of a server running with
+// cfg on this host.
+func adminURL(cfg *config.Config) string {
+	bind := cfg.WebBind
+	if cfg.AdminBind != "" {
+		bind = cfg.AdminBind
+	}
+	return localURL(bind)
+}
+
+// localURL returns the URL to reach a server listening on bind from this
+// host.
+func localURL(bind string) string {
+	host, port, err := net.SplitHostPort(bind)
+	if err != nil {
+		return "http://" + bind
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// adminGet sends an authenticated GET request to the admin API and decodes
+// the JSON response into v.
+func adminGet(cfg *config.Config, u string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.AdminToken)
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, e.Error)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// printHistory prints entries as a table.
+func printHistory(entries []history.Entry, tz *time.Location) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RECEIVED\tID\tLEVEL\tTYPE\tTARGET\tSTATUS\tDELIVERIES")
+	for _, e := range entries {
+		a := &e.Alert
+		target := a.Data.Payload.Get("name").Str()
+		if target == "" {
+			target = a.Target.Type + "/" + a.Target.ID
+		}
+		level := a.Level
+		if a.Resolved {
+			level += " (resolved)"
+		}
+		deliveries := make([]string, 0, len(e.Deliveries))
+		for _, d := range e.Deliveries {
+			s := d.Notifier
+			if d.ChatID != 0 {
+				s += ":" + strconv.FormatInt(d.ChatID, 10)
+			}
+			s += " " + d.Status
+			if d.Ref != "" {
+				s += " #" + d.Ref
+			}
+			deliveries = append(deliveries, s)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.ReceivedAt.In(tz).Format(time.DateTime),
+			e.AlertID,
+			level,
+			a.Data.Type,
+			target,
+			e.Status,
+			strings.Join(deliveries, ", "),
+		)
+	}
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	f := historyCmd.Flags()
+	historyFlags.addFlags(f)
+	f.BoolVar(&historyFlags.json, "json", false, "print alerts in JSON")
+}
@@ -17,8 +17,10 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
+	"fmt"
 	"io/fs"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/raohwork/komodo-tg-alerter/config"
@@ -32,7 +34,15 @@ import (
 var lintCmd = &cobra.Command{
 	Use:   "lint",
 	Short: "Lint templates to check for errors",
-	Run: func(cmd *cobra.Command, args []string) {
+	Long: `Lint templates to check for errors, rendering them with sample alerts.
+
+Template directories may also contain fixtures, alerts in
+testdata/<AlertType>/*.json (or just their payloads), each with its expected
+output in a .golden file next to it. Fixtures are rendered and compared with
+the golden files; --update writes the golden files with the output instead.
+It exits with an error if any template fails or any output differs.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
 		w := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
 		l := zerolog.New(w).With().Timestamp().Logger().Level(zerolog.TraceLevel)
 		log.Logger = l
@@ -43,10 +53,43 @@ var lintCmd = &cobra.Command{
 			templateFS = os.DirFS(cfg.CustemplatePath)
 		}
 
-		tmpl.Lint(templateFS, cfg.Timezone())
+		var failed []string
+		if err := tmpl.Lint(templateFS, cfg.Timezone(), tmpl.Format(cfg.TelegramFormat)); err != nil {
+			failed = append(failed, "templates")
+		}
+		if cfg.CustemplatePath != "" {
+			err := tmpl.LintFixtures(cfg.CustemplatePath, cfg.Timezone(), tmpl.Format(cfg.TelegramFormat), lintFlags.update)
+			if err != nil {
+				failed = append(failed, "fixtures")
+			}
+		}
+
+		for name, n := range cfg.Notifiers {
+			if n.Template == "" {
+				continue
+			}
+			fmt.Printf("🔎 Linting templates of notifier %s in %s\n", name, n.Template)
+			if err := tmpl.Lint(os.DirFS(n.Template), cfg.Timezone(), n.TemplateFormat()); err != nil {
+				failed = append(failed, "templates of notifier "+name)
+			}
+			if err := tmpl.LintFixtures(n.Template, cfg.Timezone(), n.TemplateFormat(), lintFlags.update); err != nil {
+				failed = append(failed, "fixtures of notifier "+name)
+			}
+		}
+
+		if len(failed) > 0 {
+			return fmt.Errorf("failed: %s", strings.Join(failed, ", "))
+		}
+		return nil
 	},
 }
 
+var lintFlags struct {
+	update bool
+}
+
 func init() {
 	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().BoolVar(&lintFlags.update, "update", false, "write golden files of fixtures with the output instead of comparing")
 }
@@ -0,0 +1,179 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+	"unicode/utf16"
+
+	"github.com/raohwork/komodo-tg-alerter/config"
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/notify"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// renderCmd renders an alert without sending it
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Render an alert read from a file or stdin, without sending it",
+	Long: `Render an alert read from a file or stdin, without sending it.
+
+The input is an alert as sent by Komodo, like a line of the archive (see
+archive.path). With --type, it can also be just the payload of an alert of
+that type. The alert is rendered with the templates, timezone and format of
+Telegram, or of the notifier given by --notifier, and the markup is checked
+like Telegram does.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.NewConfig()
+		// only problems are logged, the output is the message
+		w := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+		log.Logger = zerolog.New(w).With().Timestamp().Logger().Level(zerolog.WarnLevel)
+
+		var in io.Reader = os.Stdin
+		if f := renderFlags.file; f != "" && f != "-" {
+			file, err := os.Open(f)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			in = file
+		}
+		data, err := readAlert(in, renderFlags.typ)
+		if err != nil {
+			return err
+		}
+		if _, err := data.Data.Decode(); err != nil {
+			// templates still get the fields which can be decoded
+			fmt.Fprintf(os.Stderr, "⚠️ unexpected alert payload: %v\n", err)
+		}
+
+		path := cfg.CustemplatePath
+		format := tmpl.Format(cfg.TelegramFormat)
+		if name := renderFlags.notifier; name != "" && name != notify.TelegramName {
+			n, ok := cfg.Notifiers[name]
+			if !ok {
+				return fmt.Errorf("unknown notifier %q", name)
+			}
+			path, format = n.Template, n.TemplateFormat()
+		}
+		if renderFlags.templateDir != "" {
+			path = renderFlags.templateDir
+		}
+		r := tmpl.NewRendererFromPath(path, cfg.Timezone(), format)
+
+		text, err := r.RenderMessage(data)
+		if err != nil {
+			return err
+		}
+		fmt.Println(text)
+
+		format = r.Format(data.Data.Type)
+		plain, entities, err := format.Entities(text)
+		if err != nil {
+			return fmt.Errorf("invalid %s markup: %w", format, err)
+		}
+		if renderFlags.entities {
+			printEntities(plain, entities)
+		}
+		return nil
+	},
+}
+
+var renderFlags struct {
+	file        string
+	typ         string
+	templateDir string
+	notifier    string
+	entities    bool
+}
+
+// readAlert decodes an alert from in. If typ is not empty, it is the type of
+// the alert, and input without "data" is the payload of an alert at WARNING
+// level issued now.
+func readAlert(in io.Reader, typ string) (*komodo.AlertInfo, error) {
+	body, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("invalid alert: %w", err)
+	}
+
+	var ret komodo.AlertInfo
+	if _, ok := fields["data"]; !ok && typ != "" {
+		ret.Timestamp = time.Now().UnixMilli()
+		ret.Level = "WARNING"
+		if err := json.Unmarshal(body, &ret.Data.Payload); err != nil {
+			return nil, fmt.Errorf("invalid payload: %w", err)
+		}
+	} else if err := json.Unmarshal(body, &ret); err != nil {
+		return nil, fmt.Errorf("invalid alert: %w", err)
+	}
+	if typ != "" {
+		ret.Data.Type = typ
+	}
+	if ret.Data.Type == "" {
+		return nil, errors.New("alert type is missing, give it with --type")
+	}
+	return &ret, nil
+}
+
+// printEntities prints the plain text Telegram shows and the entities in it.
+func printEntities(plain string, entities []tmpl.Entity) {
+	fmt.Printf("--- plain text\n%s\n--- %d entities\n", plain, len(entities))
+	units := utf16.Encode([]rune(plain))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tOFFSET\tLENGTH\tTEXT\tEXTRA")
+	for _, e := range entities {
+		var extra string
+		switch {
+		case e.URL != "":
+			extra = e.URL
+		case e.Language != "":
+			extra = "language=" + e.Language
+		}
+		snippet := utf16.Decode(units[e.Offset : e.Offset+e.Length])
+		if len(snippet) > 40 {
+			snippet = append(snippet[:37], []rune("...")...)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%q\t%s\n", e.Type, e.Offset, e.Length, string(snippet), extra)
+	}
+	w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+
+	f := renderCmd.Flags()
+	f.StringVarP(&renderFlags.file, "file", "f", "", "file to read the alert from, stdin if empty or -")
+	f.StringVar(&renderFlags.typ, "type", "", "alert type, for input which is just the payload")
+	f.StringVar(&renderFlags.templateDir, "template-dir", "", "directory of templates to use instead of the configured ones")
+	f.StringVar(&renderFlags.notifier, "notifier", "", "render with templates and format of this notifier instead of Telegram")
+	f.BoolVar(&renderFlags.entities, "entities", false, "also print the plain text and the entities Telegram shows")
+}
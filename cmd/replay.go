@@ -0,0 +1,210 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/raohwork/komodo-tg-alerter/alerter"
+	"github.com/raohwork/komodo-tg-alerter/archive"
+	"github.com/raohwork/komodo-tg-alerter/config"
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// replayCmd resends archived or captured alerts
+var replayCmd = &cobra.Command{
+	Use:   "replay [file or directory...]",
+	Short: "Render and send archived or captured alerts again",
+	Long: `Render and send archived or captured alerts again, like after a template bug.
+
+Alerts are read from files or directories (see archive.path), or from the
+history of the running server with --from-history and its filters. They are
+routed with the current configuration and sent as new messages, without
+deduplication, silences or grouping.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if len(args) == 0 && !replayFlags.fromHistory {
+			return errors.New("give files or directories to replay, or --from-history")
+		}
+		if len(args) > 0 && replayFlags.fromHistory {
+			return errors.New("files and --from-history cannot be used together")
+		}
+
+		cfg := config.NewConfig()
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+		// only problems are logged, the output tells what is replayed
+		w := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+		log.Logger = zerolog.New(w).With().Timestamp().Logger().Level(zerolog.WarnLevel)
+
+		var alerts []komodo.AlertInfo
+		if replayFlags.fromHistory {
+			entries, err := replayFlags.fetch(cfg)
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				alerts = append(alerts, e.Alert)
+			}
+		}
+		for _, path := range args {
+			loaded, err := archive.Load(path)
+			if err != nil {
+				return err
+			}
+			alerts = append(alerts, loaded...)
+		}
+
+		if len(alerts) == 0 {
+			fmt.Println("no alerts to replay")
+			return nil
+		}
+
+		a, err := newReplayAlerter(cfg)
+		if err != nil {
+			return err
+		}
+		failed := replay(ctx, a, alerts, cfg.Timezone())
+		if failed > 0 {
+			return fmt.Errorf("%d of %d alerts failed", failed, len(alerts))
+		}
+		return nil
+	},
+}
+
+var replayFlags struct {
+	alertQuery
+	fromHistory bool
+	dryRun      bool
+	interval    time.Duration
+}
+
+// newReplayAlerter creates an Alerter rendering and routing alerts like the
+// server, with nothing but the notifiers. Messages are sent directly instead
+// of through the queue, and resolved alerts are sent as new messages.
+func newReplayAlerter(cfg *config.Config) (*alerter.Alerter, error) {
+	var tgapi *bot.Bot
+	if cfg.UseTelegram() {
+		var opts []bot.Option
+		if replayFlags.dryRun {
+			opts = append(opts, bot.WithSkipGetMe())
+		}
+		var err error
+		if tgapi, err = newBot(cfg, opts...); err != nil {
+			return nil, fmt.Errorf("failed to create telegram bot: %w", err)
+		}
+	}
+	channels, err := newChannels(cfg, tgapi)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifiers: %w", err)
+	}
+	db, err := store.Open("")
+	if err != nil {
+		return nil, err
+	}
+	return &alerter.Alerter{
+		DB:           db,
+		Channels:     channels,
+		Routes:       cfg.Routes,
+		DefaultRoute: cfg.DefaultRoute(),
+		ResolveMode:  alerter.ResolveSend,
+	}, nil
+}
+
+// replay renders alerts and sends them, or prints them with --dry-run, and
+// returns the number of alerts which failed.
+func replay(ctx context.Context, a *alerter.Alerter, alerts []komodo.AlertInfo, tz *time.Location) int {
+	failed := 0
+	var last time.Time
+	for i := range alerts {
+		data := &alerts[i]
+		fmt.Printf("[%d/%d] %s %s %s %s/%s\n", i+1, len(alerts),
+			data.IssuedAt().In(tz).Format(time.DateTime),
+			data.Level, data.Data.Type, data.Target.Type, data.Target.ID)
+
+		msgs, err := a.Messages(data)
+		if errors.Is(err, route.ErrNoRoute) {
+			fmt.Println("  skipped: no route matched")
+			continue
+		}
+		if err != nil {
+			fmt.Printf("  ❌ %v\n", err)
+			failed++
+			continue
+		}
+
+		ok := true
+		for _, msg := range msgs {
+			dest := msg.Notifier
+			if msg.ChatID != 0 {
+				dest += ":" + strconv.FormatInt(msg.ChatID, 10)
+			}
+			if replayFlags.dryRun {
+				fmt.Printf("  → %s (route %s)\n---\n%s\n---\n", dest, msg.Route, msg.Text)
+				continue
+			}
+
+			if wait := replayFlags.interval - time.Since(last); !last.IsZero() && wait > 0 {
+				select {
+				case <-ctx.Done():
+					return failed + len(alerts) - i
+				case <-time.After(wait):
+				}
+			}
+			last = time.Now()
+			err := a.Deliver(ctx, &queue.Job{Message: msg, CreatedAt: last})
+			if err != nil {
+				fmt.Printf("  ❌ %s: %v\n", dest, err)
+				ok = false
+				continue
+			}
+			fmt.Printf("  ✅ %s\n", dest)
+		}
+		if !ok {
+			failed++
+		}
+	}
+	return failed
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	f := replayCmd.Flags()
+	replayFlags.addFlags(f)
+	f.BoolVar(&replayFlags.fromHistory, "from-history", false, "replay alerts from the history of the running server, selected by the filters")
+	f.BoolVar(&replayFlags.dryRun, "dry-run", false, "print the messages instead of sending them")
+	f.DurationVar(&replayFlags.interval, "interval", 3*time.Second, "minimum time between messages sent")
+}
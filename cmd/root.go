@@ -62,6 +62,9 @@ func init() {
 	f.Int64P("chat", "c", 0, "Telegram chat ID to send alerts to")
 	viper.BindPFlag("telegram.chat", f.Lookup("chat"))
 
+	f.StringP("data", "d", "", "directory to keep pending deliveries and other state (in memory if empty)")
+	viper.BindPFlag("data.path", f.Lookup("data"))
+
 	f.StringP("timezone", "z", "UTC", "Timezone for time formatting")
 	viper.BindPFlag("general.timezone", f.Lookup("timezone"))
 
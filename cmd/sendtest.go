@@ -0,0 +1,338 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/raohwork/komodo-tg-alerter/alerter"
+	"github.com/raohwork/komodo-tg-alerter/config"
+	"github.com/raohwork/komodo-tg-alerter/history"
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// sendTestCmd sends an alert through the whole pipeline
+var sendTestCmd = &cobra.Command{
+	Use:   "send-test [alert type]",
+	Short: "Send a sample or custom alert like Komodo would",
+	Long: `Send a sample or custom alert like Komodo would, to check the token, chat IDs,
+routing and templates end to end.
+
+The alert is the sample of the given type (Test by default, see "kta lint"
+for the samples), or read from a file with --file. It goes through silences,
+deduplication, routing, rendering and delivery like an alert received by the
+webhook, then the chats which received it are printed with the message IDs.
+Grouping is skipped so the alert is sent right away.
+
+The test reads silences, acknowledgements and deduplication state from the
+data store (data.path), but changes nothing in it: the alert is not recorded
+in the history or the deduplication state, its messages are not tracked as
+open alerts (so they are not edited when resolved), and deliveries still
+retrying when the command exits are dropped.
+
+The data store cannot be opened while the server is running; use --via-http
+to post the alert to the running server instead. It is then handled like any
+alert from Komodo: recorded, deduplicated, grouped and retried by the server.
+Its deliveries are printed only if web.admin.token is set.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		cfg := config.NewConfig()
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+		// only problems are logged, the output tells what is sent
+		w := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+		log.Logger = zerolog.New(w).With().Timestamp().Logger().Level(zerolog.WarnLevel)
+
+		data, err := testAlert(args)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("sending %s alert (%s) about %s/%s\n",
+			data.Data.Type, data.Level, data.Target.Type, data.Target.ID)
+
+		ctx, cancel := context.WithTimeout(ctx, sendTestFlags.timeout)
+		defer cancel()
+		var e *history.Entry
+		if sendTestFlags.viaHTTP {
+			e, err = sendTestHTTP(ctx, cfg, data)
+		} else {
+			e, err = sendTestLocal(ctx, cfg, data)
+		}
+		if err != nil || e == nil {
+			return err
+		}
+		if n := printDeliveries(e); n > 0 {
+			return fmt.Errorf("%d of %d deliveries are not sent", n, len(e.Deliveries))
+		}
+		return nil
+	},
+}
+
+var sendTestFlags struct {
+	file    string
+	viaHTTP bool
+	server  string
+	timeout time.Duration
+}
+
+// testAlert returns the alert given by --file, or the sample of the type in
+// args.
+func testAlert(args []string) (*komodo.AlertInfo, error) {
+	if f := sendTestFlags.file; f != "" {
+		if len(args) > 0 {
+			return nil, errors.New("alert type and --file cannot be used together")
+		}
+		var in io.Reader = os.Stdin
+		if f != "-" {
+			file, err := os.Open(f)
+			if err != nil {
+				return nil, err
+			}
+			defer file.Close()
+			in = file
+		}
+		return readAlert(in, "")
+	}
+
+	typ := "Test"
+	if len(args) > 0 {
+		typ = args[0]
+	}
+	ret := tmpl.Sample(typ)
+	if ret == nil {
+		return nil, fmt.Errorf("no sample of alert type %q, choose one of %s",
+			typ, strings.Join(tmpl.SampleTypes(), ", "))
+	}
+	return ret, nil
+}
+
+// sendTestLocal dispatches data with the notifiers and the data store in cfg,
+// and waits for the first delivery attempts until ctx is done.
+func sendTestLocal(ctx context.Context, cfg *config.Config, data *komodo.AlertInfo) (*history.Entry, error) {
+	db, err := store.Open(cfg.DataPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w; if the server is running, use --via-http", err)
+	}
+	defer db.Close()
+
+	var tgapi *bot.Bot
+	if cfg.UseTelegram() {
+		if tgapi, err = newBot(cfg); err != nil {
+			return nil, fmt.Errorf("failed to create telegram bot: %w", err)
+		}
+	}
+	channels, err := newChannels(cfg, tgapi)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifiers: %w", err)
+	}
+	a, err := newAlerter(cfg, db, channels)
+	if err != nil {
+		return nil, err
+	}
+	// silences, acknowledgements and deduplication are read from db, but the
+	// test leaves nothing behind: its jobs and history are kept in memory,
+	// so jobs still unfinished on exit are dropped rather than sent later
+	// by the server
+	mem, err := store.Open("")
+	if err != nil {
+		return nil, err
+	}
+	if a.Queue, err = queue.New(mem, cfg.RetryMin, cfg.RetryMax); err != nil {
+		return nil, err
+	}
+	a.History = history.New(mem, cfg.HistoryRetain, cfg.HistoryMax)
+	a.Test = true
+
+	// the attempt in progress is finished before the data store is
+	// closed, unless it is out of time
+	stopCtx, stopQueue := context.WithCancel(context.Background())
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		a.Queue.Run(ctx, stopCtx, a.Deliver)
+	}()
+	defer func() {
+		stopQueue()
+		<-queueDone
+	}()
+
+	id, jobs, err := a.Dispatch(data)
+	if err != nil {
+		return nil, fmt.Errorf("alert %s is not sent: %w", id, err)
+	}
+	fmt.Printf("alert %s queued for %d chats\n", id, len(jobs))
+	for _, job := range jobs {
+		a.Queue.Wait(ctx, job)
+	}
+	return a.History.Get(id)
+}
+
+// sendTestHTTP posts data to the webhook of the running server, and waits
+// for the first delivery attempts with the admin API until ctx is done. It
+// returns nil if the admin API is disabled.
+func sendTestHTTP(ctx context.Context, cfg *config.Config, data *komodo.AlertInfo) (*history.Entry, error) {
+	base := localURL(cfg.WebBind)
+	if sendTestFlags.server != "" {
+		base = strings.TrimRight(sendTestFlags.server, "/")
+	}
+	u := base + "/"
+	if cfg.AuthPath != "" {
+		u += cfg.AuthPath
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.AuthBearer != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.AuthBearer)
+	}
+	if cfg.AuthHMAC != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(cfg.AuthHMAC))
+		mac.Write([]byte(ts + "."))
+		mac.Write(body)
+		req.Header.Set(alerter.TimestampHeader, ts)
+		req.Header.Set(alerter.SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var r alerter.Response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("%s: %w", resp.Status, err)
+	}
+	switch r.Status {
+	case alerter.StatusSent, alerter.StatusQueued, alerter.StatusGrouped:
+	default:
+		return nil, fmt.Errorf("alert %s is %s (%s): %s", r.AlertID, r.Status, resp.Status, r.Error)
+	}
+	fmt.Printf("alert %s %s (%s)\n", r.AlertID, r.Status, resp.Status)
+
+	if cfg.AdminToken == "" {
+		fmt.Println("set web.admin.token to see which chats received it")
+		return nil, nil
+	}
+	if cfg.AdminBind != "" || sendTestFlags.server == "" {
+		base = adminURL(cfg)
+	}
+	u = base + "/api/alerts/" + url.PathEscape(r.AlertID)
+	for {
+		var e history.Entry
+		if err := adminGet(cfg, u, &e); err != nil {
+			return nil, err
+		}
+		if attempted(&e) {
+			return &e, nil
+		}
+		select {
+		case <-ctx.Done():
+			return &e, nil
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// attempted reports whether every delivery of e has been attempted.
+func attempted(e *history.Entry) bool {
+	for _, d := range e.Deliveries {
+		if d.Status == history.DeliveryPending {
+			return false
+		}
+	}
+	return true
+}
+
+// printDeliveries prints where e is delivered, and returns the number of
+// deliveries which are not sent.
+func printDeliveries(e *history.Entry) int {
+	failed := 0
+	for _, d := range e.Deliveries {
+		dest := d.Notifier
+		if d.ChatID != 0 {
+			dest += ":" + strconv.FormatInt(d.ChatID, 10)
+		}
+		if d.ThreadID != 0 {
+			dest += "/" + strconv.Itoa(d.ThreadID)
+		}
+		switch d.Status {
+		case history.DeliverySent:
+			if d.Ref == "" {
+				fmt.Printf("  ✅ %s (route %s): sent\n", dest, d.Route)
+			} else {
+				fmt.Printf("  ✅ %s (route %s): message %s\n", dest, d.Route, d.Ref)
+			}
+			continue
+		case history.DeliveryPending:
+			fmt.Printf("  ⏳ %s (route %s): not attempted yet\n", dest, d.Route)
+		case history.DeliveryGrouped:
+			fmt.Printf("  ⏳ %s (route %s): waiting for digest\n", dest, d.Route)
+		case history.DeliveryRetrying:
+			fmt.Printf("  ⏳ %s (route %s): %s, will be retried\n", dest, d.Route, d.Error)
+		default:
+			fmt.Printf("  ❌ %s (route %s): %s\n", dest, d.Route, d.Error)
+		}
+		failed++
+	}
+	return failed
+}
+
+func init() {
+	rootCmd.AddCommand(sendTestCmd)
+
+	f := sendTestCmd.Flags()
+	f.StringVarP(&sendTestFlags.file, "file", "f", "", "send the alert in this file instead of a sample, - for stdin")
+	f.BoolVar(&sendTestFlags.viaHTTP, "via-http", false, "post the alert to the webhook of the running server")
+	f.StringVar(&sendTestFlags.server, "server", "", "URL of the running server (default from web.bind)")
+	f.DurationVar(&sendTestFlags.timeout, "timeout", 30*time.Second, "how long to wait for the first delivery attempts")
+}
@@ -17,15 +17,31 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/raohwork/komodo-tg-alerter/alerter"
+	"github.com/raohwork/komodo-tg-alerter/archive"
+	"github.com/raohwork/komodo-tg-alerter/botcmd"
 	"github.com/raohwork/komodo-tg-alerter/config"
-	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/dedup"
+	"github.com/raohwork/komodo-tg-alerter/health"
+	"github.com/raohwork/komodo-tg-alerter/history"
+	"github.com/raohwork/komodo-tg-alerter/metrics"
+	"github.com/raohwork/komodo-tg-alerter/notify"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/report"
+	"github.com/raohwork/komodo-tg-alerter/silence"
+	"github.com/raohwork/komodo-tg-alerter/store"
 	"github.com/raohwork/komodo-tg-alerter/tmpl"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
@@ -35,8 +51,10 @@ import (
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Start the Komodo Telegram Alerter server",
-	Run: func(cmd *cobra.Command, args []string) {
-		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	// errors are about serving, not about how kta is invoked
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
 
 		cfg := config.NewConfig()
@@ -44,7 +62,6 @@ var serveCmd = &cobra.Command{
 			log.Fatal().Err(err).Msg("invalid configuration")
 		}
 
-		renderer := tmpl.NewRendererFromPath(cfg.CustemplatePath, cfg.Timezone())
 		l, closeLogFile, err := cfg.GetLogger()
 		if err != nil {
 			log.Fatal().Err(err).Msg("failed to initialize logger")
@@ -52,50 +69,331 @@ var serveCmd = &cobra.Command{
 		defer closeLogFile()
 		log.Logger = l
 
-		tgapi, err := bot.New(cfg.TelegramToken)
+		var tgapi *bot.Bot
+		if cfg.UseTelegram() {
+			tgapi, err = newBot(cfg)
+			if err != nil {
+				l.Fatal().Err(err).Msg("failed to create telegram bot")
+			}
+		}
+		channels, err := newChannels(cfg, tgapi)
 		if err != nil {
-			l.Fatal().Err(err).Msg("failed to create telegram bot")
+			l.Fatal().Err(err).Msg("failed to create notifiers")
 		}
 
-		l.Info().Msg("Starting Komodo Telegram Alerter")
-		srv := &http.Server{
-			Addr: cfg.WebBind,
-			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				var data komodo.AlertInfo
-				err := json.NewDecoder(r.Body).Decode(&data)
-				if err != nil {
-					l.Error().Err(err).Msg("failed to decode request body")
-					return
-				}
+		for name, ch := range channels {
+			if err := ch.Renderer.Watch(ctx); err != nil {
+				l.Warn().Err(err).Str("notifier", name).Msg("templates will not be reloaded on change")
+			}
+		}
 
-				msg, err := renderer.Render(&data)
-				if err != nil {
-					l.Error().Err(err).Msg("failed to render message")
-					return
-				}
+		db, err := store.Open(cfg.DataPath)
+		if err != nil {
+			l.Fatal().Err(err).Msg("failed to open data store")
+		}
+		defer db.Close()
+		if !db.Persistent() {
+			l.Warn().Msg("data.path is not set, pending deliveries and open alerts will be lost on restart")
+		}
 
-				l.Info().Msgf("Rendered message:\n%s", msg)
+		a, err := newAlerter(cfg, db, channels)
+		if err != nil {
+			l.Fatal().Err(err).Msg("failed to create alerter")
+		}
+		q := a.Queue
+		metrics.QueueDepth(q.Len)
+		if len(cfg.Groups) > 0 {
+			if _, err := a.NewGrouper(cfg.Groups, cfg.Timezone()); err != nil {
+				l.Fatal().Err(err).Msg("failed to create grouper")
+			}
+		}
+		if cfg.ArchivePath != "" {
+			a.Archive, err = archive.New(cfg.ArchivePath, cfg.Timezone())
+			if err != nil {
+				l.Fatal().Err(err).Msg("failed to open archive")
+			}
+		}
+		if cfg.Heartbeat.Enabled() {
+			a.NewHeartbeat(cfg.Heartbeat.Interval, cfg.HeartbeatChats())
+			defer a.Heartbeat.Stop()
+			metrics.LastHeartbeat(a.Heartbeat.Last)
+		}
 
-				_, err = tgapi.SendMessage(ctx, &bot.SendMessageParams{
-					ChatID:    cfg.TelegramChatID,
-					Text:      msg,
-					ParseMode: models.ParseModeMarkdown,
-				})
-				if err != nil {
-					l.Error().Err(err).Msg("failed to send telegram message")
-					return
+		l.Info().Msg("Starting Komodo Telegram Alerter")
+		auth := &alerter.Auth{
+			Bearer:     cfg.AuthBearer,
+			PathSecret: cfg.AuthPath,
+			HMACSecret: cfg.AuthHMAC,
+			MaxSkew:    cfg.AuthMaxSkew,
+		}
+		if !auth.Enabled() {
+			l.Warn().Msg("web.auth is not configured, anyone can send alerts through kta")
+		}
+		mux := http.NewServeMux()
+		// endpoints other than webhooks, served by mux unless
+		// web.admin.bind is set
+		admin := mux
+		if cfg.AdminBind != "" {
+			admin = http.NewServeMux()
+		}
+		if cfg.AdminToken != "" {
+			api := http.NewServeMux()
+			a.Silences.Register(api)
+			a.History.Register(api)
+			admin.Handle("/api/", (&alerter.Auth{Bearer: cfg.AdminToken}).Wrap(api))
+		}
+		if cfg.Metrics {
+			admin.Handle("GET /metrics", metrics.Handler())
+		}
+		newHealth(cfg, tgapi, db, channels).Register(admin)
+		mux.Handle(heartbeatPath(cfg), heartbeatAuth(auth).Wrap(http.HandlerFunc(a.ServeHeartbeat)))
+		mux.Handle("/", auth.Wrap(a))
+		if cfg.TelegramUpdates != "" {
+			startCommands(ctx, cfg, tgapi, a, mux)
+		}
+		servers := []*http.Server{{Addr: cfg.WebBind, Handler: mux}}
+		if cfg.AdminBind != "" {
+			servers = append(servers, &http.Server{Addr: cfg.AdminBind, Handler: admin})
+		}
+		listeners := make([]net.Listener, 0, len(servers))
+		for _, srv := range servers {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				for _, ln := range listeners {
+					ln.Close()
 				}
-			}),
+				return fmt.Errorf("failed to listen on %s: %w", srv.Addr, err)
+			}
+			listeners = append(listeners, ln)
 		}
+
+		// the queue outlives ctx to deliver what webhooks accepted while
+		// shutting down, and deliveries are aborted at the shutdown deadline
+		sendCtx, abortSend := context.WithCancel(context.Background())
+		defer abortSend()
+		stopCtx, stopQueue := context.WithCancel(context.Background())
+		queueDone := make(chan struct{})
 		go func() {
-			srv.ListenAndServe()
-			os.Exit(0)
+			defer close(queueDone)
+			q.Run(sendCtx, stopCtx, a.Deliver)
 		}()
 
-		<-ctx.Done()
+		if len(cfg.Reports) > 0 {
+			(&report.Reporter{
+				Alerter: a,
+				History: a.History,
+				Reports: cfg.Reports,
+				TZ:      cfg.Timezone(),
+			}).Run(ctx)
+		}
+
+		serveErr := make(chan error, len(servers))
+		for i, srv := range servers {
+			go func() {
+				err := srv.Serve(listeners[i])
+				if !errors.Is(err, http.ErrServerClosed) {
+					serveErr <- fmt.Errorf("serve %s: %w", srv.Addr, err)
+				}
+			}()
+			l.Info().Str("bind", srv.Addr).Msg("listening")
+		}
+
+		select {
+		case <-ctx.Done():
+			l.Info().Msg("shutting down")
+		case err = <-serveErr:
+			l.Error().Err(err).Msg("server failed, shutting down")
+		}
+		stop()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		context.AfterFunc(shutdownCtx, abortSend)
+		for _, srv := range servers {
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				l.Warn().Err(err).Str("bind", srv.Addr).Msg("failed to finish pending requests")
+			}
+		}
+		if a.Groups != nil {
+			// digests waiting for more alerts are sent early rather than lost
+			a.Groups.Flush()
+		}
+		// the message being sent is finished, or aborted at the deadline,
+		// before draining the rest and closing the data store
+		stopQueue()
+		<-queueDone
+		if left := q.Drain(shutdownCtx, a.Deliver); left > 0 {
+			l.Warn().Int("pending", left).Msg("undelivered messages left in queue")
+		}
+		l.Info().Msg("Komodo Telegram Alerter stopped")
+		return err
 	},
 }
 
+// newAlerter creates an Alerter with the delivery queue, deduplication,
+// silences and history kept in db. Grouping, the archive and the heartbeat
+// are left to the caller.
+func newAlerter(cfg *config.Config, db *store.DB, channels map[string]*alerter.Channel) (*alerter.Alerter, error) {
+	q, err := queue.New(db, cfg.RetryMin, cfg.RetryMax)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delivery queue: %w", err)
+	}
+	a := &alerter.Alerter{
+		Queue:        q,
+		DB:           db,
+		Channels:     channels,
+		Routes:       cfg.Routes,
+		DefaultRoute: cfg.DefaultRoute(),
+		Wait:         cfg.QueueWait,
+		ResolveMode:  cfg.ResolveMode,
+		History:      history.New(db, cfg.HistoryRetain, cfg.HistoryMax),
+	}
+	if cfg.Dedup.Enabled() {
+		a.Dedup = dedup.New(cfg.Dedup, db)
+	}
+	a.Silences, err = silence.New(db, cfg.Silences)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load silences: %w", err)
+	}
+	return a, nil
+}
+
+// heartbeatPath returns the path of the heartbeat endpoint, which is under
+// web.auth.path if it is set.
+func heartbeatPath(cfg *config.Config) string {
+	if cfg.AuthPath != "" {
+		return "/" + cfg.AuthPath + "/heartbeat"
+	}
+	return "/heartbeat"
+}
+
+// heartbeatAuth returns auth without the path secret, which heartbeatPath
+// contains already.
+func heartbeatAuth(auth *alerter.Auth) *alerter.Auth {
+	return &alerter.Auth{
+		Bearer:     auth.Bearer,
+		HMACSecret: auth.HMACSecret,
+		MaxSkew:    auth.MaxSkew,
+	}
+}
+
+// newHealth creates readiness checks of templates, the Telegram bot and the
+// data store.
+func newHealth(cfg *config.Config, tgapi *bot.Bot, db *store.DB, channels map[string]*alerter.Channel) *health.Health {
+	h := &health.Health{}
+	h.Add("templates", func(context.Context) error {
+		var errs []error
+		for name, ch := range channels {
+			if err := ch.Renderer.Err(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+	if tgapi != nil {
+		h.Add("telegram", health.Cached(time.Minute, func(ctx context.Context) error {
+			_, err := tgapi.GetMe(ctx)
+			return err
+		}))
+	}
+	h.Add("store", func(context.Context) error {
+		return db.Ping()
+	})
+	return h
+}
+
+// newBot creates the Telegram bot with extra options, logging errors with
+// zerolog and ignoring updates no command handles.
+func newBot(cfg *config.Config, extra ...bot.Option) (*bot.Bot, error) {
+	opts := []bot.Option{
+		bot.WithDefaultHandler(func(context.Context, *bot.Bot, *models.Update) {}),
+		bot.WithErrorsHandler(func(err error) {
+			log.Error().Err(err).Msg("telegram bot error")
+		}),
+	}
+	opts = append(opts, extra...)
+	if cfg.TelegramUpdates == "webhook" {
+		opts = append(opts, bot.WithWebhookSecretToken(cfg.WebhookSecret))
+	}
+	return bot.New(cfg.TelegramToken, opts...)
+}
+
+// startCommands registers bot commands and starts receiving updates, either
+// with long polling or with a Telegram webhook served by mux.
+func startCommands(ctx context.Context, cfg *config.Config, tgapi *bot.Bot, a *alerter.Alerter, mux *http.ServeMux) {
+	admins := map[int64]bool{}
+	for _, id := range cfg.TelegramAdmins {
+		admins[id] = true
+	}
+	if len(admins) == 0 {
+		log.Warn().Msg("telegram.admins is empty, nobody can run commands which change anything")
+	}
+
+	cmds := &botcmd.Commands{
+		Alerter:  a,
+		Silences: a.Silences,
+		Chats:    cfg.TelegramChats(),
+		Admins:   admins,
+		TZ:       cfg.Timezone(),
+		Started:  time.Now(),
+	}
+	cmds.Register(tgapi)
+	cmds.RegisterButtons(tgapi)
+	if err := cmds.SetMyCommands(ctx, tgapi); err != nil {
+		log.Warn().Err(err).Msg("failed to publish bot commands")
+	}
+
+	if cfg.TelegramUpdates == "webhook" {
+		_, err := tgapi.SetWebhook(ctx, &bot.SetWebhookParams{
+			URL:         cfg.WebhookURL,
+			SecretToken: cfg.WebhookSecret,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to set telegram webhook")
+		}
+		mux.Handle(cfg.WebhookPath(), tgapi.WebhookHandler())
+		go tgapi.StartWebhook(ctx)
+		log.Info().Str("path", cfg.WebhookPath()).Msg("receiving telegram commands with webhook")
+		return
+	}
+
+	// getUpdates does not work while a webhook is set
+	if _, err := tgapi.DeleteWebhook(ctx, &bot.DeleteWebhookParams{}); err != nil {
+		log.Warn().Err(err).Msg("failed to delete telegram webhook")
+	}
+	go tgapi.Start(ctx)
+	log.Info().Msg("receiving telegram commands with long polling")
+}
+
+// newChannels creates the Telegram notifier (if tgapi is not nil) and all
+// notifiers in configuration, each with the renderer of its templates.
+func newChannels(cfg *config.Config, tgapi *bot.Bot) (map[string]*alerter.Channel, error) {
+	ret := map[string]*alerter.Channel{}
+	if tgapi != nil {
+		ret[notify.TelegramName] = &alerter.Channel{
+			Notifier: &notify.Telegram{
+				Bot:       tgapi,
+				Buttons:   cfg.TelegramButtons,
+				KomodoURL: cfg.KomodoURL,
+			},
+			Renderer: tmpl.NewRendererFromPath(cfg.CustemplatePath, cfg.Timezone(), tmpl.Format(cfg.TelegramFormat)),
+		}
+	}
+
+	for name, c := range cfg.Notifiers {
+		n, err := notify.New(&c)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", name, err)
+		}
+		ret[name] = &alerter.Channel{
+			Notifier: n,
+			Renderer: tmpl.NewRendererFromPath(c.Template, cfg.Timezone(), c.TemplateFormat()),
+		}
+	}
+
+	return ret, nil
+}
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
 }
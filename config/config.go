@@ -22,9 +22,21 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/raohwork/komodo-tg-alerter/dedup"
+	"github.com/raohwork/komodo-tg-alerter/group"
+	"github.com/raohwork/komodo-tg-alerter/heartbeat"
+	"github.com/raohwork/komodo-tg-alerter/history"
+	"github.com/raohwork/komodo-tg-alerter/notify"
+	"github.com/raohwork/komodo-tg-alerter/report"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/silence"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
 	"github.com/rs/zerolog"
 	"github.com/spf13/viper"
 )
@@ -32,11 +44,122 @@ import (
 type Config struct {
 	TelegramToken   string
 	TelegramChatID  int64
+	TelegramThread  int
 	WebBind         string
+	ShutdownTimeout time.Duration
+	AuthBearer      string
+	AuthPath        string
+	AuthHMAC        string
+	AuthMaxSkew     time.Duration
+	AdminToken      string
+	AdminBind       string
+	Metrics         bool
 	CustemplatePath string
 	LogLevel        string
 	LogFile         string
 	TZ              string
+	DataPath        string
+	RetryMin        time.Duration
+	RetryMax        time.Duration
+	QueueWait       time.Duration
+	ResolveMode     string
+	TelegramUpdates string
+	TelegramAdmins  []int64
+	WebhookURL      string
+	WebhookSecret   string
+	TelegramButtons map[string][]string
+	TelegramFormat  string
+	KomodoURL       string
+	Routes          []route.Route
+	Notifiers       map[string]notify.Config
+	Dedup           dedup.Config
+	Silences        []silence.Config
+	Groups          []group.Config
+	Reports         []report.Config
+	HistoryRetain   time.Duration
+	HistoryMax      int
+	ArchivePath     string
+	Heartbeat       heartbeat.Config
+
+	routesErr    error
+	notifiersErr error
+	silencesErr  error
+	groupsErr    error
+	reportsErr   error
+	heartbeatErr error
+	adminsErr    error
+}
+
+// HeartbeatChats returns where heartbeat notices are sent.
+func (c *Config) HeartbeatChats() []route.Destination {
+	if len(c.Heartbeat.Chats) > 0 {
+		return c.Heartbeat.Chats
+	}
+	return c.DefaultRoute()
+}
+
+// DefaultRoute returns the destination used when no route matches.
+func (c *Config) DefaultRoute() []route.Destination {
+	if c.TelegramChatID == 0 {
+		return nil
+	}
+	return []route.Destination{{
+		Notifier: notify.TelegramName,
+		Chat:     c.TelegramChatID,
+		Thread:   c.TelegramThread,
+	}}
+}
+
+// UseTelegram reports whether any alert can be sent to Telegram.
+func (c *Config) UseTelegram() bool {
+	if c.TelegramChatID != 0 {
+		return true
+	}
+	for _, r := range c.Routes {
+		for _, d := range r.Chats {
+			if d.Notifier == notify.TelegramName {
+				return true
+			}
+		}
+	}
+	for _, r := range c.Reports {
+		for _, d := range r.Chats {
+			if d.Notifier == notify.TelegramName {
+				return true
+			}
+		}
+	}
+	for _, d := range c.Heartbeat.Chats {
+		if d.Notifier == notify.TelegramName {
+			return true
+		}
+	}
+	return false
+}
+
+// TelegramChats returns all Telegram chats alerts are sent to.
+func (c *Config) TelegramChats() map[int64]bool {
+	ret := map[int64]bool{}
+	if c.TelegramChatID != 0 {
+		ret[c.TelegramChatID] = true
+	}
+	for _, r := range c.Routes {
+		for _, d := range r.Chats {
+			if d.Notifier == notify.TelegramName {
+				ret[d.Chat] = true
+			}
+		}
+	}
+	return ret
+}
+
+// WebhookPath returns the path Telegram posts updates to in webhook mode.
+func (c *Config) WebhookPath() string {
+	u, err := url.Parse(c.WebhookURL)
+	if err != nil {
+		return ""
+	}
+	return u.Path
 }
 
 func (c *Config) Timezone() *time.Location {
@@ -45,11 +168,35 @@ func (c *Config) Timezone() *time.Location {
 }
 
 func (c *Config) Validate() error {
-	if c.TelegramToken == "" {
+	if c.TelegramChatID == 0 && len(c.Routes) == 0 {
+		return errors.New("telegram.chat is not set")
+	}
+	if c.UseTelegram() && c.TelegramToken == "" {
 		return errors.New("telegram.token is not set")
 	}
-	if c.TelegramChatID == 0 {
-		return errors.New("telegram.chat is not set")
+
+	if c.notifiersErr != nil {
+		return fmt.Errorf("notifiers: %w", c.notifiersErr)
+	}
+	for name, n := range c.Notifiers {
+		if name == notify.TelegramName {
+			return fmt.Errorf("notifiers: %s is reserved for the built-in notifier", name)
+		}
+		if err := n.Validate(); err != nil {
+			return fmt.Errorf("notifiers.%s: %w", name, err)
+		}
+	}
+
+	if c.routesErr != nil {
+		return fmt.Errorf("routes: %w", c.routesErr)
+	}
+	if err := route.Validate(c.Routes); err != nil {
+		return fmt.Errorf("routes: %w", err)
+	}
+	for _, r := range c.Routes {
+		if err := c.validateChats(r.Chats); err != nil {
+			return fmt.Errorf("routes: %s: %w", r.Name, err)
+		}
 	}
 
 	_, err := zerolog.ParseLevel(c.LogLevel)
@@ -65,6 +212,156 @@ func (c *Config) Validate() error {
 		return errors.New("failed to load timezone: nil time.Location returned without error")
 	}
 
+	switch c.ResolveMode {
+	case "edit", "reply", "send":
+	default:
+		return errors.New("telegram.resolve must be one of edit, reply and send")
+	}
+
+	switch tmpl.Format(c.TelegramFormat) {
+	case tmpl.FormatMarkdownV2, tmpl.FormatHTML, tmpl.FormatPlain:
+	default:
+		return errors.New("telegram.format must be one of markdownv2, html and plain")
+	}
+
+	switch c.TelegramUpdates {
+	case "":
+	case "polling", "webhook":
+		if !c.UseTelegram() {
+			return errors.New("telegram.updates is set but nothing is sent to telegram")
+		}
+	default:
+		return errors.New("telegram.updates must be empty, polling or webhook")
+	}
+	if c.TelegramUpdates == "webhook" {
+		u, err := url.Parse(c.WebhookURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("telegram.webhook.url must be a https URL")
+		}
+		if strings.TrimLeft(u.Path, "/") == "" {
+			return errors.New("telegram.webhook.url must have a path")
+		}
+		if c.WebhookSecret == "" {
+			return errors.New("telegram.webhook.secret is not set")
+		}
+	}
+	for typ, names := range c.TelegramButtons {
+		for _, name := range names {
+			switch name {
+			case notify.ButtonAck, notify.ButtonSilence:
+				if c.TelegramUpdates == "" {
+					return fmt.Errorf("telegram.buttons.%s: %s button requires telegram.updates", typ, name)
+				}
+			case notify.ButtonOpen:
+				if c.KomodoURL == "" {
+					return fmt.Errorf("telegram.buttons.%s: %s button requires komodo.url", typ, name)
+				}
+			default:
+				return fmt.Errorf("telegram.buttons.%s: unknown button %q", typ, name)
+			}
+		}
+	}
+	if c.KomodoURL != "" {
+		u, err := url.Parse(c.KomodoURL)
+		if err != nil || u.Host == "" {
+			return errors.New("komodo.url must be an absolute URL")
+		}
+	}
+	if c.adminsErr != nil {
+		return fmt.Errorf("telegram.admins: %w", c.adminsErr)
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		return errors.New("web.shutdown_timeout must be positive")
+	}
+	if c.AdminBind != "" && c.AdminBind == c.WebBind {
+		return errors.New("web.admin.bind must differ from web.bind")
+	}
+
+	if c.AuthHMAC != "" && c.AuthMaxSkew <= 0 {
+		return errors.New("web.auth.max_skew must be positive")
+	}
+
+	if c.Dedup.Window < 0 {
+		return errors.New("dedup.window must not be negative")
+	}
+	if c.Dedup.FlapCount < 0 {
+		return errors.New("dedup.flap.count must not be negative")
+	}
+	if c.Dedup.FlapCount > 0 && c.Dedup.FlapPeriod <= 0 {
+		return errors.New("dedup.flap.period must be positive")
+	}
+
+	if c.silencesErr != nil {
+		return fmt.Errorf("silences: %w", c.silencesErr)
+	}
+	for _, sc := range c.Silences {
+		if _, err := sc.Silence(); err != nil {
+			return fmt.Errorf("silences: %w", err)
+		}
+	}
+
+	if c.groupsErr != nil {
+		return fmt.Errorf("groups: %w", c.groupsErr)
+	}
+	if err := group.Validate(c.Groups); err != nil {
+		return fmt.Errorf("groups: %w", err)
+	}
+
+	if c.reportsErr != nil {
+		return fmt.Errorf("reports: %w", c.reportsErr)
+	}
+	if err := report.Validate(c.Reports); err != nil {
+		return fmt.Errorf("reports: %w", err)
+	}
+	for _, r := range c.Reports {
+		if err := c.validateChats(r.Chats); err != nil {
+			return fmt.Errorf("reports: %s: %w", r.Name, err)
+		}
+	}
+	if c.HistoryRetain <= 0 {
+		return errors.New("history.retention must be positive")
+	}
+	if c.HistoryMax <= 0 {
+		return errors.New("history.max_entries must be positive")
+	}
+
+	if c.heartbeatErr != nil {
+		return fmt.Errorf("heartbeat.chats: %w", c.heartbeatErr)
+	}
+	if err := c.Heartbeat.Validate(); err != nil {
+		return fmt.Errorf("heartbeat: %w", err)
+	}
+	if err := c.validateChats(c.Heartbeat.Chats); err != nil {
+		return fmt.Errorf("heartbeat.chats: %w", err)
+	}
+	if c.Heartbeat.Enabled() && len(c.HeartbeatChats()) == 0 {
+		return errors.New("heartbeat.chats is not set")
+	}
+
+	if c.RetryMin <= 0 {
+		return errors.New("queue.retry_min must be positive")
+	}
+	if c.RetryMax < c.RetryMin {
+		return errors.New("queue.retry_max must not be less than queue.retry_min")
+	}
+
+	return nil
+}
+
+// validateChats checks that destinations use known notifiers.
+func (c *Config) validateChats(chats []route.Destination) error {
+	for _, d := range chats {
+		if d.Notifier == notify.TelegramName {
+			if d.Chat == 0 {
+				return errors.New("chat is not set")
+			}
+			continue
+		}
+		if _, ok := c.Notifiers[d.Notifier]; !ok {
+			return fmt.Errorf("unknown notifier %s", d.Notifier)
+		}
+	}
 	return nil
 }
 
@@ -81,6 +378,7 @@ func (c *Config) GetLogger() (logger zerolog.Logger, close func(), err error) {
 		}
 		w = io.MultiWriter(w, zerolog.SyncWriter(f))
 		close = func() {
+			f.Sync()
 			f.Close()
 		}
 	}
@@ -93,13 +391,85 @@ func NewConfig() *Config {
 	viper.SetDefault("web.bind", ":8964")
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("general.timezone", "UTC")
-	return &Config{
+	viper.SetDefault("web.auth.max_skew", "5m")
+	viper.SetDefault("web.shutdown_timeout", "8s")
+	viper.SetDefault("telegram.resolve", "edit")
+	viper.SetDefault("telegram.format", string(tmpl.FormatMarkdownV2))
+	viper.SetDefault("dedup.flap.period", "30m")
+	viper.SetDefault("queue.retry_min", "5s")
+	viper.SetDefault("queue.retry_max", "10m")
+	viper.SetDefault("history.retention", "720h")
+	viper.SetDefault("history.max_entries", history.DefaultMaxEntries)
+	ret := &Config{
 		TelegramToken:   viper.GetString("telegram.token"),
 		TelegramChatID:  viper.GetInt64("telegram.chat"),
+		TelegramThread:  viper.GetInt("telegram.thread"),
 		WebBind:         viper.GetString("web.bind"),
+		ShutdownTimeout: viper.GetDuration("web.shutdown_timeout"),
+		AuthBearer:      viper.GetString("web.auth.bearer"),
+		AuthPath:        strings.Trim(viper.GetString("web.auth.path"), "/"),
+		AuthHMAC:        viper.GetString("web.auth.hmac"),
+		AuthMaxSkew:     viper.GetDuration("web.auth.max_skew"),
+		AdminToken:      viper.GetString("web.admin.token"),
+		AdminBind:       viper.GetString("web.admin.bind"),
+		Metrics:         viper.GetBool("web.metrics"),
 		CustemplatePath: viper.GetString("template.path"),
 		LogLevel:        viper.GetString("log.level"),
 		LogFile:         viper.GetString("log.file"),
 		TZ:              viper.GetString("general.timezone"),
+		DataPath:        viper.GetString("data.path"),
+		RetryMin:        viper.GetDuration("queue.retry_min"),
+		RetryMax:        viper.GetDuration("queue.retry_max"),
+		QueueWait:       viper.GetDuration("queue.wait"),
+		ResolveMode:     viper.GetString("telegram.resolve"),
+		TelegramUpdates: viper.GetString("telegram.updates"),
+		WebhookURL:      viper.GetString("telegram.webhook.url"),
+		WebhookSecret:   viper.GetString("telegram.webhook.secret"),
+		TelegramButtons: viper.GetStringMapStringSlice("telegram.buttons"),
+		TelegramFormat:  viper.GetString("telegram.format"),
+		KomodoURL:       viper.GetString("komodo.url"),
+		HistoryRetain:   viper.GetDuration("history.retention"),
+		HistoryMax:      viper.GetInt("history.max_entries"),
+		ArchivePath:     viper.GetString("archive.path"),
+		Heartbeat: heartbeat.Config{
+			Interval: viper.GetDuration("heartbeat.interval"),
+		},
+		Dedup: dedup.Config{
+			Window:     viper.GetDuration("dedup.window"),
+			Fields:     viper.GetStringMapStringSlice("dedup.fields"),
+			FlapCount:  viper.GetInt("dedup.flap.count"),
+			FlapPeriod: viper.GetDuration("dedup.flap.period"),
+		},
+	}
+	ret.notifiersErr = viper.UnmarshalKey("notifiers", &ret.Notifiers)
+	ret.routesErr = viper.UnmarshalKey("routes", &ret.Routes)
+	ret.silencesErr = viper.UnmarshalKey("silences", &ret.Silences)
+	ret.groupsErr = viper.UnmarshalKey("groups", &ret.Groups)
+	ret.reportsErr = viper.UnmarshalKey("reports", &ret.Reports)
+	ret.heartbeatErr = viper.UnmarshalKey("heartbeat.chats", &ret.Heartbeat.Chats)
+	for _, id := range viper.GetStringSlice("telegram.admins") {
+		v, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			ret.adminsErr = fmt.Errorf("invalid user ID %q", id)
+			break
+		}
+		ret.TelegramAdmins = append(ret.TelegramAdmins, v)
+	}
+	for _, r := range ret.Routes {
+		defaultNotifier(r.Chats)
+	}
+	for _, r := range ret.Reports {
+		defaultNotifier(r.Chats)
+	}
+	defaultNotifier(ret.Heartbeat.Chats)
+	return ret
+}
+
+// defaultNotifier sends to Telegram if the notifier is not set.
+func defaultNotifier(chats []route.Destination) {
+	for i := range chats {
+		if chats[i].Notifier == "" {
+			chats[i].Notifier = notify.TelegramName
+		}
 	}
 }
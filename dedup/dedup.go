@@ -0,0 +1,264 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package dedup suppresses repeated alerts and detects flapping.
+//
+// Alerts about the same condition (see komodo.AlertInfo.Key) are compared by
+// fingerprint. An alert with the same fingerprint as the last delivered one
+// is a duplicate within the window. A condition is flapping if its fingerprint
+// changes too often; a single notice is sent and further alerts are muted
+// until it settles.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/rs/zerolog/log"
+)
+
+const bucket = "dedup"
+
+// pruneInterval is how often Check removes states no longer needed.
+const pruneInterval = time.Hour
+
+// DefaultFields are payload fields included in fingerprints if not
+// configured, so a state change to another state is not a duplicate.
+var DefaultFields = map[string][]string{
+	"StackStateChange":     {"to"},
+	"ContainerStateChange": {"to"},
+}
+
+type Config struct {
+	// suppress alerts identical to the last delivered one within Window, 0
+	// to disable
+	Window time.Duration
+	// payload fields included in fingerprint by alert type, case insensitive
+	Fields map[string][]string
+	// a condition is flapping if its fingerprint changes FlapCount times
+	// within FlapPeriod, 0 to disable
+	FlapCount  int
+	FlapPeriod time.Duration
+}
+
+func (c *Config) Enabled() bool {
+	return c.Window > 0 || c.FlapCount > 0
+}
+
+func (c *Config) fields(typ string) []string {
+	for t, f := range c.Fields {
+		if strings.EqualFold(t, typ) {
+			return f
+		}
+	}
+	return DefaultFields[typ]
+}
+
+// Result is the decision made by Check.
+type Result int
+
+const (
+	Deliver       Result = iota
+	Duplicate            // same as the last delivered alert
+	Flapping             // started flapping, send a notice instead
+	StillFlapping        // muted until the condition settles
+)
+
+func (r Result) String() string {
+	switch r {
+	case Duplicate:
+		return "duplicate"
+	case Flapping:
+		return "flapping"
+	case StillFlapping:
+		return "still flapping"
+	}
+	return "deliver"
+}
+
+type state struct {
+	Fingerprint string      `json:"fingerprint"`
+	SentAt      time.Time   `json:"sent_at"`
+	Changes     []time.Time `json:"changes,omitempty"`
+	Flapping    bool        `json:"flapping,omitempty"`
+	// last time an alert about the condition is checked
+	SeenAt time.Time `json:"seen_at,omitzero"`
+}
+
+// lastActivity returns the last time st changed or is used.
+func (st *state) lastActivity() time.Time {
+	ret := st.SeenAt
+	if st.SentAt.After(ret) {
+		ret = st.SentAt
+	}
+	if n := len(st.Changes); n > 0 && st.Changes[n-1].After(ret) {
+		ret = st.Changes[n-1]
+	}
+	return ret
+}
+
+type Filter struct {
+	cfg Config
+	db  *store.DB
+	mu  sync.Mutex
+
+	lastPrune time.Time
+}
+
+// New creates a Filter keeping its state in db.
+func New(cfg Config, db *store.DB) *Filter {
+	return &Filter{cfg: cfg, db: db}
+}
+
+// Fingerprint identifies the content of an alert: its condition, level,
+// resolved state and selected payload fields.
+func (f *Filter) Fingerprint(a *komodo.AlertInfo) string {
+	h := sha256.New()
+	h.Write([]byte(a.Key()))
+	h.Write([]byte{0})
+	h.Write([]byte(a.Level))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatBool(a.Resolved)))
+	for _, field := range f.cfg.fields(a.Data.Type) {
+		h.Write([]byte{0})
+		h.Write([]byte(field + "="))
+		h.Write(a.Data.Payload.Get(field))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Check decides what to do with a, and records it.
+func (f *Filter) Check(a *komodo.AlertInfo) Result {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := a.Key()
+	var st state
+	if _, err := f.db.Get(bucket, key, &st); err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("resetting corrupted dedup state")
+		st = state{}
+	}
+
+	now := time.Now()
+	ret := f.decide(&st, f.Fingerprint(a), now)
+	st.SeenAt = now
+	if err := f.db.Put(bucket, key, &st); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("failed to save dedup state")
+	}
+
+	if now.Sub(f.lastPrune) >= pruneInterval {
+		f.lastPrune = now
+		if n, err := f.prune(now); err != nil {
+			log.Warn().Err(err).Msg("failed to prune dedup states")
+		} else if n > 0 {
+			log.Debug().Int("removed", n).Msg("dedup states pruned")
+		}
+	}
+	return ret
+}
+
+// Peek decides what to do with a like Check, without recording it.
+func (f *Filter) Peek(a *komodo.AlertInfo) Result {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var st state
+	if _, err := f.db.Get(bucket, a.Key(), &st); err != nil {
+		st = state{}
+	}
+	return f.decide(&st, f.Fingerprint(a), time.Now())
+}
+
+// Prune removes states of conditions without alerts for longer than the
+// window and the flapping period, which affect nothing any more, and returns
+// how many are removed. Conditions like containers which are gone would keep
+// them forever otherwise.
+func (f *Filter) Prune() (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.prune(time.Now())
+}
+
+func (f *Filter) prune(now time.Time) (int, error) {
+	keep := max(f.cfg.Window, f.cfg.FlapPeriod)
+	var keys []string
+	err := f.db.ForEach(bucket, func(key string, val []byte) error {
+		var st state
+		if err := json.Unmarshal(val, &st); err != nil || now.Sub(st.lastActivity()) > keep {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for i, key := range keys {
+		if err := f.db.Delete(bucket, key); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
+
+func (f *Filter) decide(st *state, fp string, now time.Time) Result {
+	changed := st.Fingerprint != "" && st.Fingerprint != fp
+	last := st.Fingerprint
+	st.Fingerprint = fp
+
+	if f.cfg.FlapCount > 0 {
+		quiet := len(st.Changes) == 0 ||
+			now.Sub(st.Changes[len(st.Changes)-1]) > f.cfg.FlapPeriod
+		if st.Flapping && quiet {
+			st.Flapping = false
+			st.Changes = nil
+		}
+
+		changes := st.Changes[:0]
+		for _, t := range st.Changes {
+			if now.Sub(t) <= f.cfg.FlapPeriod {
+				changes = append(changes, t)
+			}
+		}
+		if changed {
+			changes = append(changes, now)
+		}
+		st.Changes = changes
+
+		if st.Flapping {
+			return StillFlapping
+		}
+		if len(st.Changes) >= f.cfg.FlapCount {
+			st.Flapping = true
+			st.SentAt = now
+			return Flapping
+		}
+	}
+
+	if f.cfg.Window > 0 && last == fp && now.Sub(st.SentAt) < f.cfg.Window {
+		return Duplicate
+	}
+
+	st.SentAt = now
+	return Deliver
+}
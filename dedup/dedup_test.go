@@ -0,0 +1,104 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/store"
+)
+
+func TestPrune(t *testing.T) {
+	db, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New(Config{Window: time.Hour, FlapCount: 3, FlapPeriod: 2 * time.Hour}, db)
+
+	now := time.Now()
+	states := map[string]state{
+		"seen recently":    {Fingerprint: "a", SentAt: now.Add(-3 * time.Hour), SeenAt: now.Add(-time.Minute)},
+		"sent recently":    {Fingerprint: "a", SentAt: now.Add(-90 * time.Minute)},
+		"changed recently": {Fingerprint: "a", Changes: []time.Time{now.Add(-time.Hour)}},
+		"idle":             {Fingerprint: "a", SentAt: now.Add(-3 * time.Hour), SeenAt: now.Add(-3 * time.Hour)},
+		"idle flapping": {
+			Fingerprint: "a",
+			SentAt:      now.Add(-5 * time.Hour),
+			SeenAt:      now.Add(-3 * time.Hour),
+			Changes:     []time.Time{now.Add(-4 * time.Hour)},
+			Flapping:    true,
+		},
+	}
+	for key, st := range states {
+		if err := db.Put(bucket, key, &st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Put(bucket, "corrupted", "not a state"); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := f.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("removed %d states, want 3", n)
+	}
+	for key, removed := range map[string]bool{
+		"seen recently":    false,
+		"sent recently":    false,
+		"changed recently": false,
+		"idle":             true,
+		"idle flapping":    true,
+		"corrupted":        true,
+	} {
+		var st state
+		ok, _ := db.Get(bucket, key, &st)
+		if ok == removed {
+			t.Errorf("%s: kept is %v, want %v", key, ok, !removed)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	db, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New(Config{Window: time.Hour}, db)
+	a := &komodo.AlertInfo{
+		Level:  "WARNING",
+		Target: komodo.AlertTarget{Type: "Server", ID: "server-1"},
+		Data:   komodo.AlertData{Type: "ServerUnreachable"},
+	}
+
+	for range 2 {
+		if res := f.Peek(a); res != Deliver {
+			t.Fatalf("Peek of a new alert returned %s, want %s", res, Deliver)
+		}
+	}
+	if res := f.Check(a); res != Deliver {
+		t.Fatalf("Check of a new alert returned %s, want %s", res, Deliver)
+	}
+	if res := f.Peek(a); res != Duplicate {
+		t.Errorf("Peek of a sent alert returned %s, want %s", res, Duplicate)
+	}
+}
@@ -0,0 +1,410 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package group collects related alerts into digests, so an incident
+// triggering many alerts at once is a single message.
+//
+// Alerts selected by a rule are grouped by the values of its labels and the
+// chat they are delivered to. The first alert of a group starts a batch which
+// is sent after Wait, and the next batch of the same group is sent no earlier
+// than Interval after it. Rules with Every send batches at fixed times of the
+// day instead, for hourly or daily digests.
+//
+// Pending batches are kept in the data store, so alerts waiting for their
+// digests are not lost when kta restarts.
+package group
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/rs/zerolog/log"
+)
+
+const bucket = "groups"
+
+// Defaults of Config.Wait and Config.Interval.
+const (
+	DefaultWait     = 30 * time.Second
+	DefaultInterval = 5 * time.Minute
+)
+
+type Config struct {
+	Name  string      `mapstructure:"name"`
+	Match route.Match `mapstructure:"match"`
+	// labels alerts are grouped by: type, level, target_type, target_id or
+	// payload fields like server_name; all selected alerts are in the same
+	// group if empty
+	By []string `mapstructure:"by"`
+	// how long to wait for more alerts after the first one of a group
+	Wait time.Duration `mapstructure:"wait"`
+	// how long to wait after a batch before sending the next one of the same
+	// group
+	Interval time.Duration `mapstructure:"interval"`
+	// send batches at multiples of Every since midnight, like 1h or 24h,
+	// instead of using Wait and Interval
+	Every time.Duration `mapstructure:"every"`
+}
+
+// Validate checks a rule loaded from configuration.
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.Wait < 0 || c.Interval < 0 || c.Every < 0 {
+		return fmt.Errorf("group %s: durations must not be negative", c.Name)
+	}
+	if c.Every > 0 && (c.Wait > 0 || c.Interval > 0) {
+		return fmt.Errorf("group %s: every cannot be used with wait or interval", c.Name)
+	}
+	if c.Every > 0 && c.Every < time.Minute {
+		return fmt.Errorf("group %s: every must be at least 1m", c.Name)
+	}
+	if err := c.Match.Validate(); err != nil {
+		return fmt.Errorf("group %s: %w", c.Name, err)
+	}
+	return nil
+}
+
+// Validate checks rules loaded from configuration.
+func Validate(rules []Config) error {
+	names := map[string]bool{}
+	for _, c := range rules {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if names[c.Name] {
+			return fmt.Errorf("group %s: duplicated name", c.Name)
+		}
+		names[c.Name] = true
+	}
+	return nil
+}
+
+// Label returns the value of label name of a, see Config.By.
+func Label(a *komodo.AlertInfo, name string) string {
+	switch name {
+	case "type":
+		return a.Data.Type
+	case "level":
+		return a.Level
+	case "target_type":
+		return a.Target.Type
+	case "target_id":
+		return a.Target.ID
+	}
+	return a.Data.Payload.Get(name).String()
+}
+
+// Digest is a batch of alerts delivered together.
+type Digest struct {
+	// name of the rule
+	Group string `json:"group"`
+	// values of the labels of the rule
+	Labels map[string]string `json:"labels,omitempty"`
+	Dest   route.Delivery    `json:"dest"`
+	// IDs assigned to Alerts, in the same order
+	IDs    []string            `json:"ids"`
+	Alerts []*komodo.AlertInfo `json:"alerts"`
+	// when the first alert is added
+	Start time.Time `json:"start"`
+}
+
+// replace replaces the alert about the same condition as a, and reports
+// whether there is one.
+func (d *Digest) replace(id string, a *komodo.AlertInfo) bool {
+	key := a.Key()
+	for i, x := range d.Alerts {
+		if x.Key() == key {
+			d.IDs[i] = id
+			d.Alerts[i] = a
+			return true
+		}
+	}
+	return false
+}
+
+type batch struct {
+	digest *Digest
+	due    time.Time
+	timer  *time.Timer
+}
+
+// stored is a pending batch in the data store.
+type stored struct {
+	Digest *Digest   `json:"digest"`
+	Due    time.Time `json:"due"`
+}
+
+// sent is when the last batch of a group was sent.
+type sent struct {
+	at       time.Time
+	interval time.Duration
+}
+
+// Grouper holds alerts until their digests are due, and hands the digests to
+// a function sending them.
+type Grouper struct {
+	rules []Config
+	tz    *time.Location
+	db    *store.DB
+	send  func(*Digest)
+
+	mu      sync.Mutex
+	pending map[string]*batch
+	last    map[string]sent
+}
+
+// New creates a Grouper with rules, calling send in its own goroutine with
+// every digest when it is due. tz decides when the day starts for rules with
+// Every. Batches pending in db are loaded and sent when they are due, or
+// right away if they are overdue or their rule is gone.
+func New(rules []Config, tz *time.Location, db *store.DB, send func(*Digest)) (*Grouper, error) {
+	rules = slices.Clone(rules)
+	for i := range rules {
+		if rules[i].Every > 0 {
+			continue
+		}
+		if rules[i].Wait == 0 {
+			rules[i].Wait = DefaultWait
+		}
+		if rules[i].Interval == 0 {
+			rules[i].Interval = DefaultInterval
+		}
+	}
+	g := &Grouper{
+		rules:   rules,
+		tz:      tz,
+		db:      db,
+		send:    send,
+		pending: map[string]*batch{},
+		last:    map[string]sent{},
+	}
+
+	var corrupted []string
+	err := db.ForEach(bucket, func(key string, val []byte) error {
+		var s stored
+		if err := json.Unmarshal(val, &s); err != nil || s.Digest == nil || len(s.Digest.Alerts) == 0 {
+			log.Warn().Err(err).Msg("dropping corrupted pending digest")
+			corrupted = append(corrupted, key)
+			return nil
+		}
+		g.pending[key] = &batch{digest: s.Digest, due: s.Due}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load pending digests: %w", err)
+	}
+	for _, key := range corrupted {
+		g.delete(key)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key, b := range g.pending {
+		var interval time.Duration
+		if c := g.ruleNamed(b.digest.Group); c != nil {
+			interval = c.Interval
+		}
+		b.timer = time.AfterFunc(time.Until(b.due), func() { g.fire(key, interval) })
+	}
+	return g, nil
+}
+
+// ruleNamed returns the rule called name, or nil.
+func (g *Grouper) ruleNamed(name string) *Config {
+	for i := range g.rules {
+		if g.rules[i].Name == name {
+			return &g.rules[i]
+		}
+	}
+	return nil
+}
+
+// save stores the batch of key. Caller must hold mu.
+func (g *Grouper) save(key string, b *batch) {
+	if err := g.db.Put(bucket, key, &stored{Digest: b.digest, Due: b.due}); err != nil {
+		log.Warn().Err(err).Str("group", b.digest.Group).Msg("failed to save pending digest")
+	}
+}
+
+// forget removes the stored batch of key after it is sent, unless a new batch
+// of the same group has started meanwhile.
+func (g *Grouper) forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.pending[key]; !ok {
+		g.delete(key)
+	}
+}
+
+// delete removes the stored batch of key.
+func (g *Grouper) delete(key string) {
+	if err := g.db.Delete(bucket, key); err != nil {
+		log.Warn().Err(err).Msg("failed to remove sent digest")
+	}
+}
+
+// rule returns the first rule selecting a, or nil.
+func (g *Grouper) rule(a *komodo.AlertInfo) *Config {
+	for i := range g.rules {
+		if g.rules[i].Match.Matches(a) {
+			return &g.rules[i]
+		}
+	}
+	return nil
+}
+
+func groupKey(c *Config, dest route.Delivery, labels map[string]string) string {
+	parts := []string{c.Name, dest.Notifier, fmt.Sprint(dest.Chat), fmt.Sprint(dest.Thread)}
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		parts = append(parts, k+"="+labels[k])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// next returns when the next batch at multiples of every since midnight is
+// due.
+func next(now time.Time, every time.Duration, tz *time.Location) time.Time {
+	now = now.In(tz)
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, tz)
+	n := now.Sub(midnight)/every + 1
+	return midnight.Add(n * every)
+}
+
+// Add holds a for the digest of the first rule selecting it for dest, and
+// reports whether it does. Alerts not selected by any rule should be sent
+// right away.
+//
+// A resolved alert is held only if the alert it resolves is still waiting,
+// which it replaces; otherwise it refers to a message already sent.
+func (g *Grouper) Add(dest route.Delivery, id string, a *komodo.AlertInfo) bool {
+	c := g.rule(a)
+	if c == nil {
+		return false
+	}
+	cp := *a
+	a = &cp
+	labels := map[string]string{}
+	for _, name := range c.By {
+		labels[name] = Label(a, name)
+	}
+	key := groupKey(c, dest, labels)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if b, ok := g.pending[key]; ok {
+		if !b.digest.replace(id, a) {
+			if a.Resolved {
+				return false
+			}
+			b.digest.IDs = append(b.digest.IDs, id)
+			b.digest.Alerts = append(b.digest.Alerts, a)
+		}
+		g.save(key, b)
+		return true
+	}
+	if a.Resolved {
+		return false
+	}
+
+	now := time.Now()
+	var due time.Time
+	if c.Every > 0 {
+		due = next(now, c.Every, g.tz)
+	} else {
+		due = now.Add(c.Wait)
+		if s, ok := g.last[key]; ok && s.at.Add(s.interval).After(due) {
+			due = s.at.Add(s.interval)
+		}
+	}
+
+	b := &batch{due: due, digest: &Digest{
+		Group:  c.Name,
+		Labels: labels,
+		Dest:   dest,
+		IDs:    []string{id},
+		Alerts: []*komodo.AlertInfo{a},
+		Start:  now,
+	}}
+	g.save(key, b)
+	b.timer = time.AfterFunc(due.Sub(now), func() { g.fire(key, c.Interval) })
+	g.pending[key] = b
+	return true
+}
+
+// fire sends the batch of key.
+func (g *Grouper) fire(key string, interval time.Duration) {
+	g.mu.Lock()
+	b, ok := g.pending[key]
+	delete(g.pending, key)
+	now := time.Now()
+	for k, s := range g.last {
+		if now.Sub(s.at) > s.interval {
+			delete(g.last, k)
+		}
+	}
+	if interval > 0 {
+		g.last[key] = sent{at: now, interval: interval}
+	}
+	g.mu.Unlock()
+
+	if ok {
+		g.send(b.digest)
+		g.forget(key)
+	}
+}
+
+// Pending returns the number of alerts waiting for their digests.
+func (g *Grouper) Pending() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ret := 0
+	for _, b := range g.pending {
+		ret += len(b.digest.Alerts)
+	}
+	return ret
+}
+
+// Flush sends all pending digests now, in the calling goroutine. It is used
+// when shutting down.
+func (g *Grouper) Flush() {
+	g.mu.Lock()
+	pending := g.pending
+	g.pending = map[string]*batch{}
+	for _, b := range pending {
+		// fire does nothing if the timer has fired but not locked mu yet
+		b.timer.Stop()
+	}
+	g.mu.Unlock()
+
+	for key, b := range pending {
+		g.send(b.digest)
+		g.forget(key)
+	}
+}
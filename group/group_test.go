@@ -0,0 +1,117 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package group
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/store"
+)
+
+func storedBatches(t *testing.T, db *store.DB) int {
+	t.Helper()
+	n := 0
+	err := db.ForEach(bucket, func(string, []byte) error {
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestReload(t *testing.T) {
+	db, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := []Config{{Name: "all", Wait: time.Hour}}
+	dest := route.Delivery{Route: "default", Destination: route.Destination{Chat: 42}}
+	alert := func(id string) *komodo.AlertInfo {
+		return &komodo.AlertInfo{
+			Level:  "WARNING",
+			Target: komodo.AlertTarget{Type: "Server", ID: id},
+			Data:   komodo.AlertData{Type: "ServerUnreachable"},
+		}
+	}
+
+	g, err := New(rules, time.UTC, db, func(*Digest) { t.Error("digest sent before it is due") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Add(dest, "a1", alert("s1"))
+	g.Add(dest, "a2", alert("s2"))
+	if n := storedBatches(t, db); n != 1 {
+		t.Fatalf("%d batches stored, want 1", n)
+	}
+	// kta is killed: the timers of g are left running but never fire
+
+	sent := make(chan *Digest, 1)
+	rules[0].Wait = time.Minute
+	g2, err := New(rules, time.UTC, db, func(d *Digest) { sent <- d })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := g2.Pending(); n != 2 {
+		t.Fatalf("%d alerts pending after reload, want 2", n)
+	}
+
+	g2.Flush()
+	d := <-sent
+	if len(d.IDs) != 2 || d.IDs[0] != "a1" || d.IDs[1] != "a2" || d.Dest != dest {
+		t.Errorf("reloaded digest has IDs %v to %+v, want [a1 a2] to %+v", d.IDs, d.Dest, dest)
+	}
+	if n := storedBatches(t, db); n != 0 {
+		t.Errorf("%d batches stored after sending, want 0", n)
+	}
+}
+
+func TestReloadOverdue(t *testing.T) {
+	db, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &stored{
+		Digest: &Digest{
+			Group:  "removed rule",
+			IDs:    []string{"a1"},
+			Alerts: []*komodo.AlertInfo{{Level: "CRITICAL"}},
+		},
+		Due: time.Now().Add(-time.Minute),
+	}
+	if err := db.Put(bucket, "key", b); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := make(chan *Digest, 1)
+	if _, err := New(nil, time.UTC, db, func(d *Digest) { sent <- d }); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case d := <-sent:
+		if d.Group != "removed rule" {
+			t.Errorf("sent digest of %q, want the stored one", d.Group)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("overdue digest is not sent")
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package health serves liveness, readiness and version endpoints for
+// container orchestration.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Version is the version of kta, set at build time with
+// -ldflags "-X github.com/raohwork/komodo-tg-alerter/health.Version=v1.2.3".
+// The module version from build info is used if it is empty.
+var Version string
+
+// checkTimeout limits how long a single readiness check may take.
+const checkTimeout = 5 * time.Second
+
+// CheckFunc returns an error if the thing it checks is not ready.
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Health runs readiness checks.
+type Health struct {
+	checks []check
+}
+
+// Add adds a readiness check.
+func (h *Health) Add(name string, fn CheckFunc) {
+	h.checks = append(h.checks, check{name: name, fn: fn})
+}
+
+// Cached wraps fn so it runs at most once every d, for checks calling
+// external services.
+func Cached(d time.Duration, fn CheckFunc) CheckFunc {
+	var mu sync.Mutex
+	var last time.Time
+	var err error
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if time.Since(last) < d {
+			return err
+		}
+		err = fn(ctx)
+		last = time.Now()
+		return err
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Register adds the endpoints to mux:
+//
+//   - GET /healthz answers 200 as long as the process is alive
+//   - GET /readyz runs all checks, answering 200 if all pass or 503
+//   - GET /version returns BuildInfo
+func (h *Health) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", h.handleHealthz)
+	mux.HandleFunc("GET /readyz", h.handleReadyz)
+	mux.HandleFunc("GET /version", h.handleVersion)
+}
+
+func (h *Health) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness is the body of /readyz. Checks maps the name of each check to
+// "ok" or its error.
+type Readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func (h *Health) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	resp := Readiness{Status: "ok", Checks: map[string]string{}}
+	for _, c := range h.checks {
+		if err := c.fn(ctx); err != nil {
+			resp.Status = "failed"
+			resp.Checks[c.name] = err.Error()
+			continue
+		}
+		resp.Checks[c.name] = "ok"
+	}
+
+	code := http.StatusOK
+	if resp.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, resp)
+}
+
+// BuildInfo is the body of /version.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	Revision  string `json:"revision,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// Build returns information about the running binary.
+func Build() BuildInfo {
+	ret := BuildInfo{Version: Version}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ret
+	}
+	ret.GoVersion = info.GoVersion
+	if ret.Version == "" {
+		ret.Version = info.Main.Version
+	}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			ret.Revision = s.Value
+		case "vcs.time":
+			ret.Time = s.Value
+		case "vcs.modified":
+			ret.Modified = s.Value == "true"
+		}
+	}
+	return ret
+}
+
+func (h *Health) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Build())
+}
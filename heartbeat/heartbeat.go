@@ -0,0 +1,139 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package heartbeat notices when Komodo stops sending anything, which
+// otherwise looks exactly like nothing is wrong.
+//
+// Every alert from Komodo and every request to the heartbeat endpoint is a
+// beat. If there is no beat within the interval, a notice is sent, and
+// another one when Komodo is heard from again.
+package heartbeat
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+)
+
+type Config struct {
+	// how long Komodo may stay silent, 0 to disable
+	Interval time.Duration `mapstructure:"interval"`
+	// where to send notices, the default chat if empty
+	Chats []route.Destination `mapstructure:"chats"`
+}
+
+func (c *Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// Validate checks the configuration, except the chats.
+func (c *Config) Validate() error {
+	if c.Interval < 0 {
+		return errors.New("interval must not be negative")
+	}
+	if c.Enabled() && c.Interval < time.Minute {
+		return errors.New("interval must be at least 1m")
+	}
+	return nil
+}
+
+// Monitor waits for beats and calls a function when the deadline is missed
+// and when beats come back.
+type Monitor struct {
+	interval time.Duration
+	notify   func(*tmpl.Heartbeat)
+
+	mu      sync.Mutex
+	last    time.Time
+	missed  bool
+	stopped bool
+	timer   *time.Timer
+}
+
+// New creates a Monitor expecting a beat every interval, starting now.
+// notify is called in its own goroutine with the notice to send.
+func New(interval time.Duration, notify func(*tmpl.Heartbeat)) *Monitor {
+	m := &Monitor{
+		interval: interval,
+		notify:   notify,
+		last:     time.Now(),
+	}
+	m.timer = time.AfterFunc(interval, m.expire)
+	return m
+}
+
+// expire is called when the deadline is missed.
+func (m *Monitor) expire() {
+	m.mu.Lock()
+	now := time.Now()
+	if m.stopped || m.missed || now.Sub(m.last) < m.interval {
+		// a beat came in while the timer was firing
+		m.mu.Unlock()
+		return
+	}
+	m.missed = true
+	data := &tmpl.Heartbeat{
+		LastSeen: m.last,
+		Silent:   now.Sub(m.last),
+		Interval: m.interval,
+	}
+	m.mu.Unlock()
+
+	m.notify(data)
+}
+
+// Beat records that Komodo is alive, and sends the recovery notice if it has
+// missed the deadline.
+func (m *Monitor) Beat() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped {
+		return
+	}
+
+	now := time.Now()
+	if m.missed {
+		m.missed = false
+		go m.notify(&tmpl.Heartbeat{
+			Recovered: true,
+			LastSeen:  m.last,
+			Silent:    now.Sub(m.last),
+			Interval:  m.interval,
+		})
+	}
+	m.last = now
+	m.timer.Reset(m.interval)
+}
+
+// Last returns when Komodo is last heard from, or when the Monitor is created
+// if it has not been heard from.
+func (m *Monitor) Last() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last
+}
+
+// Stop stops waiting for beats.
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	m.timer.Stop()
+}
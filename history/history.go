@@ -0,0 +1,269 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package history keeps alerts received from Komodo and what happened to
+// them, for reports and for looking back at incidents.
+//
+// Entries are kept in the store under the alert ID, which starts with the
+// time it is received, so they are iterated in order. Entries older than the
+// retention, or beyond the maximum number of entries, are removed by Prune.
+package history
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/rs/zerolog/log"
+)
+
+const bucket = "history"
+
+// Defaults of the retention and the maximum number of entries.
+const (
+	DefaultRetention  = 30 * 24 * time.Hour
+	DefaultMaxEntries = 50000
+)
+
+// pruneInterval is how often Add removes old entries.
+const pruneInterval = time.Hour
+
+// Values of Delivery.Status.
+const (
+	DeliveryPending  = "pending"  // waiting in the delivery queue
+	DeliveryGrouped  = "grouped"  // waiting for a digest
+	DeliveryRetrying = "retrying" // failed and will be retried
+	DeliverySent     = "sent"     // delivered
+	DeliveryFailed   = "failed"   // failed and will not be retried
+)
+
+// Delivery is a message sent, or to be sent, for an alert.
+type Delivery struct {
+	Route    string `json:"route,omitempty"`
+	Notifier string `json:"notifier"`
+	ChatID   int64  `json:"chat_id,omitempty"`
+	ThreadID int    `json:"thread_id,omitempty"`
+	// rendered message, and its markup, see tmpl.Format
+	Text   string `json:"text,omitempty"`
+	Format string `json:"format,omitempty"`
+	// ID of the digest message the alert is sent in, if any
+	Digest   string `json:"digest,omitempty"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts,omitempty"`
+	Error    string `json:"error,omitempty"`
+	// message ID returned by the notifier, like the Telegram message ID
+	Ref string    `json:"ref,omitempty"`
+	At  time.Time `json:"at,omitzero"`
+}
+
+// SameChat reports whether d is sent to the chat.
+func (d *Delivery) SameChat(notifier string, chat int64, thread int) bool {
+	return d.Notifier == notifier && d.ChatID == chat && d.ThreadID == thread
+}
+
+// Entry is an alert received from Komodo.
+type Entry struct {
+	AlertID    string           `json:"alert_id"`
+	ReceivedAt time.Time        `json:"received_at"`
+	Alert      komodo.AlertInfo `json:"alert"`
+	// what happened when it was received, like "queued" or "silenced"
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+}
+
+// Delivery returns the delivery of e to a chat, or nil.
+func (e *Entry) Delivery(notifier string, chat int64, thread int) *Delivery {
+	for i := range e.Deliveries {
+		if e.Deliveries[i].SameChat(notifier, chat, thread) {
+			return &e.Deliveries[i]
+		}
+	}
+	return nil
+}
+
+type History struct {
+	db         *store.DB
+	retention  time.Duration
+	maxEntries int
+
+	// serializes updates of entries
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// New creates a History keeping at most maxEntries entries in db for
+// retention.
+func New(db *store.DB, retention time.Duration, maxEntries int) *History {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &History{db: db, retention: retention, maxEntries: maxEntries}
+}
+
+// Add records an alert received now. e.AlertID must sort after IDs of alerts
+// received before it, and e.ReceivedAt is set if it is zero. Old entries are
+// pruned once in a while.
+func (h *History) Add(e *Entry) error {
+	now := time.Now()
+	if e.ReceivedAt.IsZero() {
+		e.ReceivedAt = now
+	}
+
+	h.mu.Lock()
+	err := h.db.Put(bucket, e.AlertID, e)
+	due := now.Sub(h.lastPrune) >= pruneInterval
+	if due {
+		h.lastPrune = now
+	}
+	h.mu.Unlock()
+
+	if due {
+		if n, err := h.Prune(); err != nil {
+			log.Warn().Err(err).Msg("failed to prune alert history")
+		} else if n > 0 {
+			log.Debug().Int("removed", n).Msg("alert history pruned")
+		}
+	}
+	return err
+}
+
+// Update changes the entry of id with fn and saves it. It does nothing if
+// there is no such entry, like for messages which are not about a received
+// alert.
+func (h *History) Update(id string, fn func(*Entry)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var e Entry
+	ok, err := h.db.Get(bucket, id, &e)
+	if !ok || err != nil {
+		return err
+	}
+	fn(&e)
+	return h.db.Put(bucket, id, &e)
+}
+
+// Get returns the entry of id, or nil if there is no such entry.
+func (h *History) Get(id string) (*Entry, error) {
+	var e Entry
+	ok, err := h.db.Get(bucket, id, &e)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Since returns entries received from t, oldest first.
+func (h *History) Since(t time.Time) ([]Entry, error) {
+	return h.Query(&Query{Since: t})
+}
+
+// Query selects entries. Zero values match everything, and types and levels
+// are compared case-insensitively.
+type Query struct {
+	Type  string
+	Level string
+	// what happened when it was received, see Entry.Status
+	Status string
+	// received from Since and before Until
+	Since time.Time
+	Until time.Time
+	// resolved or not resolved alerts only
+	Resolved *bool
+	// keep only the latest Limit entries
+	Limit int
+}
+
+// Matches reports whether e is selected by q.
+func (q *Query) Matches(e *Entry) bool {
+	switch {
+	case q.Type != "" && !strings.EqualFold(q.Type, e.Alert.Data.Type):
+		return false
+	case q.Level != "" && !strings.EqualFold(q.Level, e.Alert.Level):
+		return false
+	case q.Status != "" && q.Status != e.Status:
+		return false
+	case !q.Since.IsZero() && e.ReceivedAt.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !e.ReceivedAt.Before(q.Until):
+		return false
+	case q.Resolved != nil && *q.Resolved != e.Alert.Resolved:
+		return false
+	}
+	return true
+}
+
+// Query returns entries selected by q, oldest first.
+func (h *History) Query(q *Query) ([]Entry, error) {
+	var ret []Entry
+	err := h.db.ForEach(bucket, func(key string, val []byte) error {
+		var e Entry
+		if err := json.Unmarshal(val, &e); err != nil {
+			log.Warn().Err(err).Str("key", key).Msg("skipping corrupted history entry")
+			return nil
+		}
+		if q.Matches(&e) {
+			ret = append(ret, e)
+		}
+		return nil
+	})
+	if q.Limit > 0 && len(ret) > q.Limit {
+		ret = ret[len(ret)-q.Limit:]
+	}
+	return ret, err
+}
+
+// Prune removes entries older than the retention and the oldest entries
+// beyond the maximum number, and returns how many are removed.
+func (h *History) Prune() (int, error) {
+	limit := time.Now().Add(-h.retention)
+	var keys, kept []string
+	err := h.db.ForEach(bucket, func(key string, val []byte) error {
+		var e struct {
+			ReceivedAt time.Time `json:"received_at"`
+		}
+		if err := json.Unmarshal(val, &e); err != nil || e.ReceivedAt.Before(limit) {
+			keys = append(keys, key)
+		} else {
+			kept = append(kept, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if n := len(kept) - h.maxEntries; n > 0 {
+		// keys are in the order entries are received
+		keys = append(keys, kept[:n]...)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, key := range keys {
+		if err := h.db.Delete(bucket, key); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
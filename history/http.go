@@ -0,0 +1,129 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultLimit is the number of entries returned by the API if limit is not
+// given.
+const DefaultLimit = 100
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// parseTime parses an RFC3339 time, or a duration like "24h" meaning that
+// long before now.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q: expected RFC3339 or a duration like 24h", s)
+	}
+	return t, nil
+}
+
+// ParseQuery parses the parameters of GET /api/alerts: type, level, status,
+// since and until (RFC3339 or a duration before now), resolved (true or
+// false) and limit (DefaultLimit if not given, 0 for all).
+func ParseQuery(v url.Values) (*Query, error) {
+	q := &Query{
+		Type:   v.Get("type"),
+		Level:  v.Get("level"),
+		Status: v.Get("status"),
+		Limit:  DefaultLimit,
+	}
+	now := time.Now()
+	var err error
+	if s := v.Get("since"); s != "" {
+		if q.Since, err = parseTime(s, now); err != nil {
+			return nil, fmt.Errorf("since: %w", err)
+		}
+	}
+	if s := v.Get("until"); s != "" {
+		if q.Until, err = parseTime(s, now); err != nil {
+			return nil, fmt.Errorf("until: %w", err)
+		}
+	}
+	if s := v.Get("resolved"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("resolved: invalid value %q", s)
+		}
+		q.Resolved = &b
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 0 {
+			return nil, fmt.Errorf("limit: invalid value %q", s)
+		}
+	}
+	return q, nil
+}
+
+// Register adds the admin API to mux:
+//
+//   - GET /api/alerts lists received alerts, oldest first, see ParseQuery
+//   - GET /api/alerts/{id} returns a received alert
+func (h *History) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/alerts", h.handleList)
+	mux.HandleFunc("GET /api/alerts/{id}", h.handleGet)
+}
+
+func (h *History) handleList(w http.ResponseWriter, r *http.Request) {
+	q, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, err := h.Query(q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (h *History) handleGet(w http.ResponseWriter, r *http.Request) {
+	e, err := h.Get(r.PathValue("id"))
+	switch {
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	case e == nil:
+		writeError(w, http.StatusNotFound, fmt.Errorf("alert %s not found", r.PathValue("id")))
+	default:
+		writeJSON(w, http.StatusOK, e)
+	}
+}
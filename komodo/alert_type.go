@@ -19,7 +19,11 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package komodo
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -128,6 +132,14 @@ func (p PayloadItem) Int() int64 {
 	return n
 }
 
+// String returns strings without quotes and everything else as raw JSON.
+func (p PayloadItem) String() string {
+	if p.IsStr() {
+		return p.Str()
+	}
+	return string(p)
+}
+
 type Map map[string]PayloadItem
 
 func (m Map) Get(key string) PayloadItem {
@@ -166,3 +178,55 @@ func (a *AlertInfo) IssuedAt() time.Time {
 func (a *AlertInfo) ResolvedAt() time.Time {
 	return time.UnixMilli(a.ResolveTimestamp).In(TZ)
 }
+
+// Duration returns how long the alert has been open. For a resolved alert it
+// is the time between IssuedAt and ResolvedAt.
+func (a *AlertInfo) Duration() time.Duration {
+	if a.ResolveTimestamp == 0 {
+		return time.Since(a.IssuedAt())
+	}
+	return a.ResolvedAt().Sub(a.IssuedAt())
+}
+
+// Key identifies the condition an alert is about, so the resolved version of
+// an alert has the same key as the original one.
+func (a *AlertInfo) Key() string {
+	return a.Target.Type + "/" + a.Target.ID + "/" + a.Data.Type
+}
+
+// KeyHash is a short hash of Key, for places where the key is too long like
+// Telegram callback data.
+func (a *AlertInfo) KeyHash() string {
+	return HashKey(a.Key())
+}
+
+// HashKey returns the KeyHash of an alert with key.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// uiPaths maps target types to their paths in the Komodo UI.
+var uiPaths = map[string]string{
+	"Server":         "servers",
+	"Stack":          "stacks",
+	"Deployment":     "deployments",
+	"Build":          "builds",
+	"Repo":           "repos",
+	"Procedure":      "procedures",
+	"Action":         "actions",
+	"Builder":        "builders",
+	"Alerter":        "alerters",
+	"ResourceSync":   "resource-syncs",
+	"ServerTemplate": "server-templates",
+}
+
+// URL returns the page of the target in the Komodo UI at base, or an empty
+// string if the target has no page.
+func (t AlertTarget) URL(base string) string {
+	p, ok := uiPaths[t.Type]
+	if !ok || base == "" || t.ID == "" {
+		return ""
+	}
+	return strings.TrimSuffix(base, "/") + "/" + p + "/" + url.PathEscape(t.ID)
+}
@@ -0,0 +1,327 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package komodo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownType is returned by AlertData.Decode for alert types without a
+// Payload type.
+var ErrUnknownType = errors.New("unknown alert type")
+
+// Payload is the data of an alert of a specific type, see AlertData.Decode.
+type Payload interface {
+	// AlertType returns the value of AlertData.Type of the payload.
+	AlertType() string
+}
+
+// Resource is the common part of alerts about a resource.
+type Resource struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Server is the common part of alerts about a server.
+type Server struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Region string `json:"region,omitempty"`
+}
+
+// ServerResource is the common part of alerts about a resource running on a
+// server.
+type ServerResource struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	ServerID   string `json:"server_id"`
+	ServerName string `json:"server_name"`
+}
+
+// Serror is an error reported by Komodo. Komodo sends it as an object, but
+// a plain string is accepted too.
+type Serror struct {
+	Error string   `json:"error"`
+	Trace []string `json:"trace,omitempty"`
+}
+
+func (e *Serror) UnmarshalJSON(buf []byte) error {
+	if len(buf) > 0 && buf[0] == '"' {
+		e.Trace = nil
+		return json.Unmarshal(buf, &e.Error)
+	}
+	type plain Serror
+	return json.Unmarshal(buf, (*plain)(e))
+}
+
+// String returns the error message, or an empty string if e is nil.
+func (e *Serror) String() string {
+	if e == nil {
+		return ""
+	}
+	return e.Error
+}
+
+// Version is a version of Komodo resources. Komodo sends it as an object,
+// but a string like "v1.2.3" is accepted too.
+type Version struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+func (v *Version) UnmarshalJSON(buf []byte) error {
+	if len(buf) > 0 && buf[0] == '"' {
+		var s string
+		if err := json.Unmarshal(buf, &s); err != nil {
+			return err
+		}
+		*v = Version{}
+		if s == "" {
+			return nil
+		}
+		_, err := fmt.Sscanf(strings.TrimPrefix(s, "v"), "%d.%d.%d", &v.Major, &v.Minor, &v.Patch)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", s)
+		}
+		return nil
+	}
+	type plain Version
+	return json.Unmarshal(buf, (*plain)(v))
+}
+
+// String formats v like "v1.2.3".
+func (v Version) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Strings is a list of strings. A single string is accepted as a list of one
+// element.
+type Strings []string
+
+func (s *Strings) UnmarshalJSON(buf []byte) error {
+	if len(buf) > 0 && buf[0] == '"' {
+		var str string
+		if err := json.Unmarshal(buf, &str); err != nil {
+			return err
+		}
+		*s = Strings{str}
+		return nil
+	}
+	return json.Unmarshal(buf, (*[]string)(s))
+}
+
+// String joins the strings with ", ".
+func (s Strings) String() string {
+	return strings.Join(s, ", ")
+}
+
+type None struct{}
+
+type Test struct{ Resource }
+
+type ServerVersionMismatch struct {
+	Server
+	ServerVersion string `json:"server_version"`
+	CoreVersion   string `json:"core_version"`
+}
+
+type ServerUnreachable struct {
+	Server
+	Err *Serror `json:"err,omitempty"`
+}
+
+type ServerCpu struct {
+	Server
+	Percentage float64 `json:"percentage"`
+}
+
+type ServerMem struct {
+	Server
+	UsedGB  float64 `json:"used_gb"`
+	TotalGB float64 `json:"total_gb"`
+}
+
+type ServerDisk struct {
+	Server
+	Path    string  `json:"path"`
+	UsedGB  float64 `json:"used_gb"`
+	TotalGB float64 `json:"total_gb"`
+}
+
+type ContainerStateChange struct {
+	ServerResource
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type DeploymentImageUpdateAvailable struct {
+	ServerResource
+	Image string `json:"image"`
+}
+
+type DeploymentAutoUpdated struct {
+	ServerResource
+	Image string `json:"image"`
+}
+
+type StackStateChange struct {
+	ServerResource
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type StackImageUpdateAvailable struct {
+	ServerResource
+	Service string `json:"service"`
+	Image   string `json:"image"`
+}
+
+type StackAutoUpdated struct {
+	ServerResource
+	Images Strings `json:"images"`
+}
+
+type AwsBuilderTerminationFailed struct {
+	InstanceID string `json:"instance_id"`
+	Message    string `json:"message"`
+}
+
+type ResourceSyncPendingUpdates struct{ Resource }
+
+type BuildFailed struct {
+	Resource
+	Version Version `json:"version"`
+}
+
+type RepoBuildFailed struct{ Resource }
+
+type ProcedureFailed struct{ Resource }
+
+type ActionFailed struct{ Resource }
+
+type ScheduleRun struct {
+	Resource
+	ResourceType string `json:"resource_type"`
+}
+
+type Custom struct {
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+func (None) AlertType() string                           { return "None" }
+func (Test) AlertType() string                           { return "Test" }
+func (ServerVersionMismatch) AlertType() string          { return "ServerVersionMismatch" }
+func (ServerUnreachable) AlertType() string              { return "ServerUnreachable" }
+func (ServerCpu) AlertType() string                      { return "ServerCpu" }
+func (ServerMem) AlertType() string                      { return "ServerMem" }
+func (ServerDisk) AlertType() string                     { return "ServerDisk" }
+func (ContainerStateChange) AlertType() string           { return "ContainerStateChange" }
+func (DeploymentImageUpdateAvailable) AlertType() string { return "DeploymentImageUpdateAvailable" }
+func (DeploymentAutoUpdated) AlertType() string          { return "DeploymentAutoUpdated" }
+func (StackStateChange) AlertType() string               { return "StackStateChange" }
+func (StackImageUpdateAvailable) AlertType() string      { return "StackImageUpdateAvailable" }
+func (StackAutoUpdated) AlertType() string               { return "StackAutoUpdated" }
+func (AwsBuilderTerminationFailed) AlertType() string    { return "AwsBuilderTerminationFailed" }
+func (ResourceSyncPendingUpdates) AlertType() string     { return "ResourceSyncPendingUpdates" }
+func (BuildFailed) AlertType() string                    { return "BuildFailed" }
+func (RepoBuildFailed) AlertType() string                { return "RepoBuildFailed" }
+func (ProcedureFailed) AlertType() string                { return "ProcedureFailed" }
+func (ActionFailed) AlertType() string                   { return "ActionFailed" }
+func (ScheduleRun) AlertType() string                    { return "ScheduleRun" }
+func (Custom) AlertType() string                         { return "Custom" }
+
+// payloadTypes maps known alert types to their Payload types.
+var payloadTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, p := range []Payload{
+		None{}, Test{}, ServerVersionMismatch{}, ServerUnreachable{},
+		ServerCpu{}, ServerMem{}, ServerDisk{}, ContainerStateChange{},
+		DeploymentImageUpdateAvailable{}, DeploymentAutoUpdated{},
+		StackStateChange{}, StackImageUpdateAvailable{}, StackAutoUpdated{},
+		AwsBuilderTerminationFailed{}, ResourceSyncPendingUpdates{},
+		BuildFailed{}, RepoBuildFailed{}, ProcedureFailed{}, ActionFailed{},
+		ScheduleRun{}, Custom{},
+	} {
+		payloadTypes[p.AlertType()] = reflect.TypeOf(p)
+	}
+}
+
+// KnownTypes returns the alert types with a Payload type.
+func KnownTypes() []string {
+	return slices.Sorted(maps.Keys(payloadTypes))
+}
+
+// Decode returns the payload as a pointer to the Payload type of the alert
+// type, like *ServerDisk, or ErrUnknownType.
+//
+// Like json.Unmarshal, fields of the wrong type are skipped: the error
+// describes the first of them, and the returned Payload has the others.
+func (d AlertData) Decode() (Payload, error) {
+	t, ok := payloadTypes[d.Type]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownType, d.Type)
+	}
+	p := reflect.New(t).Interface().(Payload)
+	buf, err := json.Marshal(d.Payload)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(buf, p); err != nil {
+		return p, fmt.Errorf("decode %s: %w", d.Type, err)
+	}
+	return p, nil
+}
+
+// Typed returns the payload decoded by Decode, or the generic Payload map if
+// the alert type is unknown. Errors are ignored as templates cannot handle
+// them; use Decode to see them.
+func (d AlertData) Typed() any {
+	p, err := d.Decode()
+	if errors.Is(err, ErrUnknownType) {
+		return d.Payload
+	}
+	return p
+}
+
+// NewAlertData creates AlertData with the payload p.
+func NewAlertData(p Payload) AlertData {
+	d := AlertData{Type: p.AlertType(), Payload: Map{}}
+	buf, err := json.Marshal(p)
+	if err != nil {
+		panic(fmt.Sprintf("encode %s: %v", p.AlertType(), err))
+	}
+	if err := json.Unmarshal(buf, &d.Payload); err != nil {
+		panic(fmt.Sprintf("encode %s: %v", p.AlertType(), err))
+	}
+	return d
+}
+
+// Typed returns the payload of the alert, see AlertData.Typed. Templates use
+// it like {{ .Typed.UsedGB }}.
+func (a *AlertInfo) Typed() any {
+	return a.Data.Typed()
+}
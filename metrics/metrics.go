@@ -0,0 +1,120 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package metrics exposes Prometheus metrics of kta.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kta"
+
+// Registry holds all metrics of kta, plus Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// alerts received from Komodo by alert type and level
+	Received = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alerts_received_total",
+		Help:      "Alerts received from Komodo.",
+	}, []string{"type", "level"})
+
+	// alerts not delivered by reason: duplicate, flapping, acknowledged or
+	// silenced
+	Suppressed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alerts_suppressed_total",
+		Help:      "Alerts not delivered because they are duplicated, flapping, acknowledged or silenced.",
+	}, []string{"reason"})
+
+	// templates failed to render by notifier and alert type
+	RenderFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "render_failures_total",
+		Help:      "Alerts whose template failed to render.",
+	}, []string{"notifier", "type"})
+
+	// delivery attempts by notifier and result, which is "ok" or the class
+	// of the error, see notify.ErrorClass
+	Deliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "deliveries_total",
+		Help:      "Delivery attempts by result.",
+	}, []string{"notifier", "result"})
+
+	// time from queueing a message to delivering it, including retries
+	DeliveryLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "delivery_latency_seconds",
+		Help:      "Time from queueing a message to delivering it.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600},
+	}, []string{"notifier"})
+
+	// when a message was delivered successfully for the last time
+	LastDelivery = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_delivery_timestamp_seconds",
+		Help:      "Unix time of the last successful delivery.",
+	}, []string{"notifier"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Received,
+		Suppressed,
+		RenderFailures,
+		Deliveries,
+		DeliveryLatency,
+		LastDelivery,
+	)
+}
+
+// QueueDepth reports the number of pending deliveries returned by fn.
+func QueueDepth(fn func() int) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Messages waiting in the delivery queue.",
+	}, func() float64 {
+		return float64(fn())
+	}))
+}
+
+// LastHeartbeat reports when Komodo is last heard from, returned by fn.
+func LastHeartbeat(fn func() time.Time) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_heartbeat_timestamp_seconds",
+		Help:      "Unix time Komodo is last heard from.",
+	}, func() float64 {
+		return float64(fn().Unix())
+	}))
+}
+
+// Handler serves metrics in Prometheus format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
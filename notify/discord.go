@@ -0,0 +1,65 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/raohwork/komodo-tg-alerter/queue"
+)
+
+// Discord sends alerts to a Discord channel through an incoming webhook.
+type Discord struct {
+	URL string
+}
+
+type discordMessage struct {
+	ID      string `json:"id,omitempty"`
+	Content string `json:"content"`
+}
+
+// endpoint returns the webhook URL with path appended and query merged.
+func (d *Discord) endpoint(path string, query url.Values) string {
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return d.URL
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	q := u.Query()
+	for k, v := range query {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (d *Discord) Send(ctx context.Context, msg *queue.Message) (string, error) {
+	var resp discordMessage
+	// wait=true makes Discord return the created message
+	err := doRequest(ctx, http.MethodPost, d.endpoint("", url.Values{"wait": {"true"}}), nil,
+		discordMessage{Content: msg.Text}, &resp)
+	return resp.ID, err
+}
+
+func (d *Discord) Edit(ctx context.Context, msg *queue.Message, ref, text string) error {
+	return doRequest(ctx, http.MethodPatch, d.endpoint("/messages/"+ref, nil), nil,
+		discordMessage{Content: text}, nil)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package notify
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/raohwork/komodo-tg-alerter/queue"
+)
+
+// Matrix sends alerts to a Matrix room with the client-server API.
+type Matrix struct {
+	Homeserver string
+	Room       string
+	Token      string
+}
+
+type matrixContent struct {
+	MsgType    string         `json:"msgtype"`
+	Body       string         `json:"body"`
+	NewContent *matrixContent `json:"m.new_content,omitempty"`
+	RelatesTo  map[string]any `json:"m.relates_to,omitempty"`
+}
+
+// txnID returns the transaction ID of sending content for msg. It is the
+// same for every attempt, so the homeserver ignores a retry of an event it
+// has received, and differs for other events since transaction IDs must be
+// unique per access token.
+func (m *Matrix) txnID(msg *queue.Message, content *matrixContent) string {
+	buf, _ := json.Marshal(content)
+	sum := sha256.Sum256(append([]byte(m.Room+"\n"), buf...))
+	return "kta-" + msg.AlertID + "-" + hex.EncodeToString(sum[:8])
+}
+
+func (m *Matrix) send(ctx context.Context, msg *queue.Message, content *matrixContent) (string, error) {
+	txn := url.PathEscape(m.txnID(msg, content))
+	u := strings.TrimSuffix(m.Homeserver, "/") +
+		"/_matrix/client/v3/rooms/" + url.PathEscape(m.Room) +
+		"/send/m.room.message/" + txn
+
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + m.Token}
+	err := doRequest(ctx, http.MethodPut, u, headers, content, &resp)
+	return resp.EventID, err
+}
+
+func (m *Matrix) Send(ctx context.Context, msg *queue.Message) (string, error) {
+	return m.send(ctx, msg, &matrixContent{MsgType: "m.text", Body: msg.Text})
+}
+
+func (m *Matrix) Reply(ctx context.Context, msg *queue.Message, ref, text string) (string, error) {
+	return m.send(ctx, msg, &matrixContent{
+		MsgType: "m.text",
+		Body:    text,
+		RelatesTo: map[string]any{
+			"m.in_reply_to": map[string]string{"event_id": ref},
+		},
+	})
+}
+
+func (m *Matrix) Edit(ctx context.Context, msg *queue.Message, ref, text string) error {
+	_, err := m.send(ctx, msg, &matrixContent{
+		MsgType:    "m.text",
+		Body:       "* " + text,
+		NewContent: &matrixContent{MsgType: "m.text", Body: text},
+		RelatesTo: map[string]any{
+			"rel_type": "m.replace",
+			"event_id": ref,
+		},
+	})
+	return err
+}
@@ -0,0 +1,60 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/raohwork/komodo-tg-alerter/queue"
+)
+
+func TestMatrixTxnID(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"event_id":"$e"}`))
+	}))
+	defer srv.Close()
+
+	m := &Matrix{Homeserver: srv.URL, Room: "!room:example.com", Token: "t"}
+	other := &Matrix{Homeserver: srv.URL, Room: "!other:example.com", Token: "t"}
+	msg := &queue.Message{AlertID: "a1", Text: "hello"}
+	ctx := context.Background()
+
+	m.Send(ctx, msg)                                          // 0
+	m.Send(ctx, msg)                                          // 1: a retry
+	m.Send(ctx, &queue.Message{AlertID: "a2", Text: "hello"}) // 2
+	m.Edit(ctx, msg, "$e", "hello\n\nacked")                  // 3
+	m.Edit(ctx, msg, "$e", "hello\n\nresolved")               // 4
+	other.Send(ctx, msg)                                      // 5
+
+	if paths[0] != paths[1] {
+		t.Errorf("retry uses another transaction: %s and %s", paths[0], paths[1])
+	}
+	// other events use their own transactions
+	seen := map[string]int{}
+	for i := 1; i < len(paths); i++ {
+		if j, ok := seen[paths[i]]; ok {
+			t.Errorf("requests %d and %d use the same transaction %s", j, i, paths[i])
+		}
+		seen[paths[i]] = i
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package notify delivers rendered alerts to chat services.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+)
+
+// Notifier delivers rendered alerts.
+type Notifier interface {
+	// Send delivers msg.Text and returns the ID of the sent message, which is
+	// empty if the service does not provide one. Errors which retrying
+	// cannot fix are wrapped by queue.Permanent.
+	Send(ctx context.Context, msg *queue.Message) (string, error)
+}
+
+// Editor is implemented by notifiers which can replace the text of a sent
+// message.
+type Editor interface {
+	Edit(ctx context.Context, msg *queue.Message, ref, text string) error
+}
+
+// Replier is implemented by notifiers which can reply to a sent message.
+type Replier interface {
+	Reply(ctx context.Context, msg *queue.Message, ref, text string) (string, error)
+}
+
+// TelegramName is the name of the built-in Telegram notifier.
+const TelegramName = "telegram"
+
+// Notifier types.
+const (
+	TypeDiscord = "discord"
+	TypeSlack   = "slack"
+	TypeMatrix  = "matrix"
+	TypeNtfy    = "ntfy"
+	TypeWebhook = "webhook"
+)
+
+// Config configures a notifier in the notifiers section of config file.
+type Config struct {
+	Type string `mapstructure:"type"`
+	// webhook URL, or topic URL for ntfy
+	URL string `mapstructure:"url"`
+	// access token for matrix and ntfy
+	Token      string `mapstructure:"token"`
+	Homeserver string `mapstructure:"homeserver"`
+	Room       string `mapstructure:"room"`
+	// extra headers for generic webhook
+	Headers map[string]string `mapstructure:"headers"`
+	// custom template path, embedded templates are used if empty
+	Template string `mapstructure:"template"`
+	// markup of templates, see tmpl.Format
+	Format string `mapstructure:"format"`
+}
+
+var defaultFormats = map[string]tmpl.Format{
+	TypeDiscord: tmpl.FormatDiscord,
+	TypeSlack:   tmpl.FormatSlack,
+	TypeMatrix:  tmpl.FormatPlain,
+	TypeNtfy:    tmpl.FormatPlain,
+	TypeWebhook: tmpl.FormatPlain,
+}
+
+// TemplateFormat returns the format templates of this notifier are written in.
+func (c *Config) TemplateFormat() tmpl.Format {
+	if c.Format != "" {
+		return tmpl.Format(c.Format)
+	}
+	return defaultFormats[c.Type]
+}
+
+func (c *Config) Validate() error {
+	if _, ok := defaultFormats[c.Type]; !ok {
+		return fmt.Errorf("unknown type %q", c.Type)
+	}
+	if !c.TemplateFormat().Valid() {
+		return fmt.Errorf("unknown format %q", c.Format)
+	}
+
+	switch c.Type {
+	case TypeMatrix:
+		if c.Homeserver == "" || c.Room == "" || c.Token == "" {
+			return errors.New("homeserver, room and token are required")
+		}
+	default:
+		if c.URL == "" {
+			return errors.New("url is required")
+		}
+	}
+	return nil
+}
+
+// New creates a notifier from c. The Telegram notifier is not configured here,
+// create a Telegram with the bot instead.
+func New(c *Config) (Notifier, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch c.Type {
+	case TypeDiscord:
+		return &Discord{URL: c.URL}, nil
+	case TypeSlack:
+		return &Slack{URL: c.URL}, nil
+	case TypeMatrix:
+		return &Matrix{Homeserver: c.Homeserver, Room: c.Room, Token: c.Token}, nil
+	case TypeNtfy:
+		return &Ntfy{URL: c.URL, Token: c.Token}, nil
+	default:
+		return &Webhook{URL: c.URL, Headers: c.Headers}, nil
+	}
+}
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// HTTPError is returned when a service responds with an unexpected status.
+type HTTPError struct {
+	Code int
+	Body string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected response %d: %s", e.Code, e.Body)
+}
+
+// ErrorClass tells what kind of failure err is, for metrics: "ok" for nil,
+// otherwise one of "bad_request", "forbidden", "unauthorized", "not_found",
+// "rate_limited", "client_error", "server_error", "timeout", "network" and
+// "other".
+func ErrorClass(err error) string {
+	var he *HTTPError
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, bot.ErrorBadRequest):
+		return "bad_request"
+	case errors.Is(err, bot.ErrorForbidden), bot.IsMigrateError(err):
+		return "forbidden"
+	case errors.Is(err, bot.ErrorUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, bot.ErrorNotFound):
+		return "not_found"
+	case errors.Is(err, bot.ErrorTooManyRequests), bot.IsTooManyRequestsError(err):
+		return "rate_limited"
+	case errors.As(err, &he):
+		switch {
+		case he.Code == http.StatusTooManyRequests:
+			return "rate_limited"
+		case he.Code >= 500:
+			return "server_error"
+		}
+		return "client_error"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		if ne.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	}
+	return "other"
+}
+
+// doRequest sends a request with body encoded as JSON if it is not a reader,
+// and decodes the response into out if it is not nil. Client errors other
+// than 408 and 429 are permanent.
+func doRequest(ctx context.Context, method, url string, headers map[string]string, body, out any) error {
+	var r io.Reader
+	isJSON := false
+	switch v := body.(type) {
+	case nil:
+	case io.Reader:
+		r = v
+	default:
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return queue.Permanent(err)
+		}
+		r = bytes.NewReader(buf)
+		isJSON = true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return queue.Permanent(err)
+	}
+	if isJSON {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		buf, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := &HTTPError{Code: resp.StatusCode, Body: string(buf)}
+		if resp.StatusCode >= 500 ||
+			resp.StatusCode == http.StatusRequestTimeout ||
+			resp.StatusCode == http.StatusTooManyRequests {
+			return err
+		}
+		return queue.Permanent(err)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/raohwork/komodo-tg-alerter/queue"
+)
+
+// Ntfy publishes alerts to a ntfy topic. URL is the topic URL like
+// https://ntfy.sh/my-topic.
+type Ntfy struct {
+	URL   string
+	Token string
+}
+
+var ntfyPriority = map[string]string{
+	"critical": "urgent",
+	"warning":  "high",
+	"ok":       "low",
+}
+
+// ntfyTitle returns the title of the notification of msg.
+func ntfyTitle(msg *queue.Message) string {
+	switch {
+	case msg.Report:
+		return "Komodo report"
+	case msg.Heartbeat:
+		return "Komodo heartbeat"
+	case msg.Digest > 0:
+		return fmt.Sprintf("Komodo digest of %d alerts", msg.Digest)
+	case msg.Flapping:
+		return "Komodo " + msg.Alert.Data.Type + " flapping"
+	case msg.Alert.Resolved:
+		return "Komodo " + msg.Alert.Data.Type + " resolved"
+	}
+	return "Komodo " + msg.Alert.Data.Type
+}
+
+func (n *Ntfy) Send(ctx context.Context, msg *queue.Message) (string, error) {
+	headers := map[string]string{"Title": ntfyTitle(msg)}
+	// reports and heartbeat notices are not about an alert
+	if level := strings.ToLower(msg.Alert.Level); level != "" {
+		headers["Tags"] = level
+		if p, ok := ntfyPriority[level]; ok && !msg.Alert.Resolved {
+			headers["Priority"] = p
+		}
+	}
+	if n.Token != "" {
+		headers["Authorization"] = "Bearer " + n.Token
+	}
+
+	var resp struct {
+		ID string `json:"id"`
+	}
+	err := doRequest(ctx, http.MethodPost, n.URL, headers, strings.NewReader(msg.Text), &resp)
+	return resp.ID, err
+}
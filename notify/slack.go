@@ -0,0 +1,37 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package notify
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/raohwork/komodo-tg-alerter/queue"
+)
+
+// Slack sends alerts to a Slack channel through an incoming webhook.
+// Incoming webhooks cannot edit messages, so resolved alerts are sent as new
+// messages.
+type Slack struct {
+	URL string
+}
+
+func (s *Slack) Send(ctx context.Context, msg *queue.Message) (string, error) {
+	body := map[string]string{"text": msg.Text}
+	return "", doRequest(ctx, http.MethodPost, s.URL, nil, body, nil)
+}
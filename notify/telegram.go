@@ -0,0 +1,187 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+)
+
+// Buttons which can be attached to Telegram messages.
+const (
+	ButtonAck     = "ack"     // acknowledge the alert
+	ButtonSilence = "silence" // silence the alert for an hour
+	ButtonOpen    = "open"    // open the target in the Komodo UI
+)
+
+// Prefixes of callback data sent by buttons, followed by the KeyHash of the
+// alert.
+const (
+	CallbackAck     = "ack:"
+	CallbackSilence = "silence:"
+)
+
+// Telegram sends alerts with a Telegram bot to msg.ChatID.
+type Telegram struct {
+	Bot *bot.Bot
+	// names of buttons attached to alerts by type, "default" for types not
+	// listed; keys are case insensitive
+	Buttons map[string][]string
+	// base URL of the Komodo UI for ButtonOpen
+	KomodoURL string
+}
+
+func (t *Telegram) buttons(typ string) []string {
+	var def []string
+	for k, v := range t.Buttons {
+		if strings.EqualFold(k, typ) {
+			return v
+		}
+		if strings.EqualFold(k, "default") {
+			def = v
+		}
+	}
+	return def
+}
+
+// markup builds the inline keyboard of msg. Resolved alerts only keep the
+// link to Komodo, acknowledged ones lose the Ack button, and digests,
+// reports and heartbeat notices have no buttons as they are not about a
+// single alert.
+func (t *Telegram) markup(msg *queue.Message) models.ReplyMarkup {
+	if msg.Digest > 0 || msg.Report || msg.Heartbeat {
+		return nil
+	}
+	var row []models.InlineKeyboardButton
+	alert := &msg.Alert
+	for _, name := range t.buttons(alert.Data.Type) {
+		switch name {
+		case ButtonAck:
+			if alert.Resolved || msg.Acked {
+				continue
+			}
+			row = append(row, models.InlineKeyboardButton{
+				Text:         "Ack",
+				CallbackData: CallbackAck + alert.KeyHash(),
+			})
+		case ButtonSilence:
+			if alert.Resolved {
+				continue
+			}
+			row = append(row, models.InlineKeyboardButton{
+				Text:         "Silence 1h",
+				CallbackData: CallbackSilence + alert.KeyHash(),
+			})
+		case ButtonOpen:
+			u := alert.Target.URL(t.KomodoURL)
+			if u == "" {
+				continue
+			}
+			row = append(row, models.InlineKeyboardButton{
+				Text: "Open in Komodo",
+				URL:  u,
+			})
+		}
+	}
+
+	if len(row) == 0 {
+		return nil
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{row}}
+}
+
+// classify marks errors which retrying cannot fix as permanent.
+func (t *Telegram) classify(err error) error {
+	if errors.Is(err, bot.ErrorBadRequest) ||
+		errors.Is(err, bot.ErrorForbidden) ||
+		bot.IsMigrateError(err) {
+		return queue.Permanent(err)
+	}
+	return err
+}
+
+// parseMode returns the parse mode of the format of msg. Messages queued
+// without a format are MarkdownV2 like the embedded templates.
+func parseMode(msg *queue.Message) models.ParseMode {
+	switch tmpl.Format(msg.Format) {
+	case tmpl.FormatHTML:
+		return models.ParseModeHTML
+	case tmpl.FormatPlain:
+		return ""
+	default:
+		return models.ParseModeMarkdown // MarkdownV2
+	}
+}
+
+func (t *Telegram) send(ctx context.Context, msg *queue.Message, text string, replyTo int) (string, error) {
+	params := &bot.SendMessageParams{
+		ChatID:          msg.ChatID,
+		MessageThreadID: msg.ThreadID,
+		Text:            text,
+		ParseMode:       parseMode(msg),
+		ReplyMarkup:     t.markup(msg),
+	}
+	if replyTo != 0 {
+		params.ReplyParameters = &models.ReplyParameters{
+			MessageID:                replyTo,
+			AllowSendingWithoutReply: true,
+		}
+	}
+
+	sent, err := t.Bot.SendMessage(ctx, params)
+	if err != nil {
+		return "", t.classify(err)
+	}
+	return strconv.Itoa(sent.ID), nil
+}
+
+func (t *Telegram) Send(ctx context.Context, msg *queue.Message) (string, error) {
+	return t.send(ctx, msg, msg.Text, 0)
+}
+
+func (t *Telegram) Reply(ctx context.Context, msg *queue.Message, ref, text string) (string, error) {
+	id, err := strconv.Atoi(ref)
+	if err != nil {
+		return "", queue.Permanent(fmt.Errorf("invalid message id %q", ref))
+	}
+	return t.send(ctx, msg, text, id)
+}
+
+func (t *Telegram) Edit(ctx context.Context, msg *queue.Message, ref, text string) error {
+	id, err := strconv.Atoi(ref)
+	if err != nil {
+		return queue.Permanent(fmt.Errorf("invalid message id %q", ref))
+	}
+
+	_, err = t.Bot.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      msg.ChatID,
+		MessageID:   id,
+		Text:        text,
+		ParseMode:   parseMode(msg),
+		ReplyMarkup: t.markup(msg),
+	})
+	return t.classify(err)
+}
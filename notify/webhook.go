@@ -0,0 +1,50 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package notify
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/queue"
+)
+
+// Webhook POSTs alerts as JSON to an arbitrary URL.
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+}
+
+// WebhookPayload is the body sent by Webhook.
+type WebhookPayload struct {
+	AlertID string           `json:"alert_id"`
+	Route   string           `json:"route"`
+	Text    string           `json:"text"`
+	Alert   komodo.AlertInfo `json:"alert"`
+}
+
+func (w *Webhook) Send(ctx context.Context, msg *queue.Message) (string, error) {
+	body := WebhookPayload{
+		AlertID: msg.AlertID,
+		Route:   msg.Route,
+		Text:    msg.Text,
+		Alert:   msg.Alert,
+	}
+	return "", doRequest(ctx, http.MethodPost, w.URL, w.Headers, body, nil)
+}
@@ -0,0 +1,358 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package queue implements the delivery queue between the webhook handler
+// and Telegram.
+//
+// Rendered messages are persisted in the store before the webhook returns,
+// and a single worker delivers them with exponential backoff. Pending jobs
+// survive restarts if the store is persistent.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/rs/zerolog/log"
+)
+
+const bucket = "queue"
+
+// Message is a rendered alert waiting to be delivered.
+type Message struct {
+	AlertID  string           `json:"alert_id"`
+	Alert    komodo.AlertInfo `json:"alert"`
+	Route    string           `json:"route,omitempty"`
+	Notifier string           `json:"notifier"`
+	ChatID   int64            `json:"chat_id,omitempty"`
+	ThreadID int              `json:"thread_id,omitempty"`
+	Text     string           `json:"text"`
+	// markup of Text, see tmpl.Format
+	Format string `json:"format,omitempty"`
+	// for resolved alerts, the footer appended to the original message
+	Footer string `json:"footer,omitempty"`
+	// Text is a notice about the alert flapping rather than the alert
+	Flapping bool `json:"flapping,omitempty"`
+	// Text is a digest of this many alerts, Alert being the first of them
+	Digest int `json:"digest,omitempty"`
+	// IDs of the alerts in the digest
+	AlertIDs []string `json:"alert_ids,omitempty"`
+	// Text is a scheduled report, Alert is empty
+	Report bool `json:"report,omitempty"`
+	// Text is a notice about Komodo being silent or back, Alert is empty
+	Heartbeat bool `json:"heartbeat,omitempty"`
+	// someone has acknowledged the alert
+	Acked bool `json:"acked,omitempty"`
+}
+
+type Job struct {
+	ID        string    `json:"id"`
+	Message   Message   `json:"message"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	NextAt    time.Time `json:"next_at"`
+	LastError string    `json:"last_error,omitempty"`
+
+	// closed after the first delivery attempt of a job pushed in this process
+	done   chan struct{}
+	result error
+}
+
+// Handler delivers a job. Returning nil removes the job from the queue,
+// returning an error wrapped by Permanent drops it, any other error schedules
+// a retry.
+type Handler func(ctx context.Context, job *Job) error
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err has been marked by Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+type Queue struct {
+	db         *store.DB
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu   sync.Mutex
+	jobs []*Job
+	seq  uint64
+	wake chan struct{}
+}
+
+// New creates a queue backed by db and loads pending jobs from it.
+func New(db *store.DB, minBackoff, maxBackoff time.Duration) (*Queue, error) {
+	q := &Queue{
+		db:         db,
+		minBackoff: minBackoff,
+		maxBackoff: maxBackoff,
+		wake:       make(chan struct{}, 1),
+	}
+
+	err := db.ForEach(bucket, func(key string, val []byte) error {
+		var job Job
+		if err := json.Unmarshal(val, &job); err != nil {
+			log.Warn().Err(err).Str("id", key).Msg("dropping corrupted queue entry")
+			return nil
+		}
+		q.jobs = append(q.jobs, &job)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load queue: %w", err)
+	}
+	if len(q.jobs) > 0 {
+		log.Info().Int("count", len(q.jobs)).Msg("loaded pending deliveries")
+	}
+
+	return q, nil
+}
+
+// Len returns the number of pending jobs.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// Push persists msg and schedules it for immediate delivery.
+func (q *Queue) Push(msg Message) (*Job, error) {
+	jobs, err := q.PushAll([]Message{msg})
+	if err != nil {
+		return nil, err
+	}
+	return jobs[0], nil
+}
+
+// PushAll persists msgs and schedules them for immediate delivery. It is all
+// or nothing: if any of them cannot be persisted, none is queued, so the
+// caller can report the failure and have the sender retry without
+// duplicating messages already delivered.
+func (q *Queue) PushAll(msgs []Message) ([]*Job, error) {
+	now := time.Now()
+
+	jobs := make([]*Job, 0, len(msgs))
+	q.mu.Lock()
+	for _, msg := range msgs {
+		q.seq++
+		jobs = append(jobs, &Job{
+			ID:        fmt.Sprintf("%016x%08x", now.UnixNano(), q.seq),
+			Message:   msg,
+			CreatedAt: now,
+			NextAt:    now,
+			done:      make(chan struct{}),
+		})
+	}
+	q.mu.Unlock()
+
+	// jobs are visible to the worker only after all of them are persisted
+	for i, job := range jobs {
+		if err := q.db.Put(bucket, job.ID, job); err != nil {
+			for _, j := range jobs[:i] {
+				if derr := q.db.Delete(bucket, j.ID); derr != nil {
+					log.Error().Err(derr).Str("id", j.ID).Msg("failed to remove unqueued job from store")
+				}
+			}
+			return nil, fmt.Errorf("persist job: %w", err)
+		}
+	}
+
+	q.mu.Lock()
+	q.jobs = append(q.jobs, jobs...)
+	q.mu.Unlock()
+	q.notify()
+
+	return jobs, nil
+}
+
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the job with the earliest delivery time. Jobs with the same
+// delivery time are returned in the order they were pushed.
+func (q *Queue) next() *Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.jobs) == 0 {
+		return nil
+	}
+	sort.SliceStable(q.jobs, func(i, j int) bool {
+		a, b := q.jobs[i], q.jobs[j]
+		if !a.NextAt.Equal(b.NextAt) {
+			return a.NextAt.Before(b.NextAt)
+		}
+		return a.ID < b.ID
+	})
+	return q.jobs[0]
+}
+
+func (q *Queue) remove(job *Job) {
+	q.mu.Lock()
+	for i, j := range q.jobs {
+		if j == job {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			break
+		}
+	}
+	q.mu.Unlock()
+
+	if err := q.db.Delete(bucket, job.ID); err != nil {
+		log.Error().Err(err).Str("id", job.ID).Msg("failed to remove delivered job from queue")
+	}
+}
+
+func (q *Queue) backoff(attempts int) time.Duration {
+	d := q.minBackoff
+	for i := 1; i < attempts && d < q.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, q.maxBackoff)
+}
+
+// Wait blocks until the first delivery attempt of job finishes or ctx is done.
+// It reports whether the attempt has finished and its result.
+func (q *Queue) Wait(ctx context.Context, job *Job) (attempted bool, err error) {
+	if job.done == nil {
+		return false, nil
+	}
+	select {
+	case <-job.done:
+		return true, job.result
+	case <-ctx.Done():
+		return false, nil
+	}
+}
+
+// Run delivers jobs with h until stop is done, and returns once the delivery
+// in progress finishes. Deliveries get ctx, so they are aborted only when ctx
+// is cancelled; stopping alone lets the one in progress finish rather than be
+// sent again by Drain.
+func (q *Queue) Run(ctx, stop context.Context, h Handler) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		job := q.next()
+		if job == nil {
+			select {
+			case <-stop.Done():
+				return
+			case <-q.wake:
+				continue
+			}
+		}
+
+		if wait := time.Until(job.NextAt); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-stop.Done():
+				return
+			case <-q.wake:
+				continue
+			case <-timer.C:
+				continue
+			}
+		}
+
+		if stop.Err() != nil {
+			return
+		}
+		q.process(ctx, job, h)
+	}
+}
+
+// Drain attempts to deliver every pending job once with h, ignoring backoff,
+// until all of them have been attempted or ctx is done. It is used on
+// shutdown after Run returns, and returns the number of jobs left.
+func (q *Queue) Drain(ctx context.Context, h Handler) int {
+	q.mu.Lock()
+	jobs := append([]*Job(nil), q.jobs...)
+	q.mu.Unlock()
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		q.process(ctx, job, h)
+	}
+	return q.Len()
+}
+
+func (q *Queue) process(ctx context.Context, job *Job, h Handler) {
+	err := h(ctx, job)
+	job.Attempts++
+	if job.Attempts == 1 && job.done != nil {
+		job.result = err
+		close(job.done)
+	}
+	if err == nil {
+		q.remove(job)
+		return
+	}
+
+	l := log.With().
+		Err(err).
+		Str("id", job.ID).
+		Int("attempts", job.Attempts).
+		Logger()
+
+	if IsPermanent(err) {
+		l.Error().Msg("delivery failed permanently, dropping message")
+		q.remove(job)
+		return
+	}
+
+	q.mu.Lock()
+	job.LastError = err.Error()
+	job.NextAt = time.Now().Add(q.backoff(job.Attempts))
+	q.mu.Unlock()
+
+	l.Warn().Time("next", job.NextAt).Msg("delivery failed, will retry")
+	if err := q.db.Put(bucket, job.ID, job); err != nil {
+		l.Error().Err(err).Msg("failed to persist retry state")
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/store"
+)
+
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	db, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := New(db, time.Second, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// stored reports whether job is persisted, and returns the persisted copy.
+func stored(t *testing.T, q *Queue, job *Job) (*Job, bool) {
+	t.Helper()
+	var ret Job
+	ok, err := q.db.Get(bucket, job.ID, &ret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ret, ok
+}
+
+func TestProcess(t *testing.T) {
+	errTemporary := errors.New("timeout")
+	errRejected := errors.New("chat not found")
+
+	cases := []struct {
+		name string
+		err  error
+		// job is kept for a retry
+		kept bool
+	}{
+		{"delivered", nil, false},
+		{"permanent error", Permanent(errRejected), false},
+		{"wrapped permanent error", errors.Join(errors.New("send"), Permanent(errRejected)), false},
+		{"temporary error", errTemporary, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q := newTestQueue(t)
+			job, err := q.Push(Message{AlertID: "a", Text: "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			q.process(context.Background(), job, func(context.Context, *Job) error {
+				return c.err
+			})
+
+			attempted, result := q.Wait(context.Background(), job)
+			if !attempted || result != c.err {
+				t.Errorf("Wait returned %v, %v; want true, %v", attempted, result, c.err)
+			}
+			if job.Attempts != 1 {
+				t.Errorf("attempts is %d, want 1", job.Attempts)
+			}
+
+			saved, ok := stored(t, q, job)
+			if !c.kept {
+				if q.Len() != 0 || ok {
+					t.Fatalf("job is kept (len %d, stored %v), want it removed", q.Len(), ok)
+				}
+				return
+			}
+			if q.Len() != 1 || !ok {
+				t.Fatalf("job is removed (len %d, stored %v), want it kept", q.Len(), ok)
+			}
+			if saved.LastError != c.err.Error() || saved.Attempts != 1 {
+				t.Errorf("stored job has error %q after %d attempts, want %q after 1",
+					saved.LastError, saved.Attempts, c.err)
+			}
+			if d := saved.NextAt.Sub(start); d < time.Second || d > 2*time.Second {
+				t.Errorf("next attempt is %v later, want about 1s", d)
+			}
+		})
+	}
+}
+
+func TestRetrySchedule(t *testing.T) {
+	q := newTestQueue(t)
+	job, err := q.Push(Message{AlertID: "a", Text: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail := func(context.Context, *Job) error { return errors.New("timeout") }
+
+	want := []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	}
+	for i, w := range want {
+		start := time.Now()
+		q.process(context.Background(), job, fail)
+		if job.Attempts != i+1 {
+			t.Fatalf("attempts is %d, want %d", job.Attempts, i+1)
+		}
+		if d := job.NextAt.Sub(start); d < w || d > w+time.Second {
+			t.Errorf("after %d attempts, next attempt is %v later, want %v", i+1, d, w)
+		}
+	}
+
+	// the first result is kept for Wait
+	if _, err := q.Wait(context.Background(), job); err == nil {
+		t.Error("Wait returned nil error after failed attempts")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	q := newTestQueue(t)
+	for _, text := range []string{"first", "second"} {
+		if _, err := q.Push(Message{AlertID: "a", Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	job := q.next()
+	q.process(context.Background(), job, func(context.Context, *Job) error {
+		return errors.New("timeout")
+	})
+
+	// a new process loads the jobs left in the store
+	loaded, err := New(q.db, time.Second, 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != 2 {
+		t.Fatalf("loaded %d jobs, want 2", loaded.Len())
+	}
+	// the untried job comes first, then the one to retry
+	if got := loaded.next(); got.Attempts != 0 {
+		t.Errorf("first loaded job has %d attempts, want the untried one", got.Attempts)
+	}
+	for _, got := range loaded.jobs {
+		if got.ID != job.ID {
+			continue
+		}
+		if got.Attempts != 1 || got.LastError != "timeout" || !got.NextAt.Equal(job.NextAt) {
+			t.Errorf("loaded job has error %q after %d attempts, next at %v; want %q after 1, next at %v",
+				got.LastError, got.Attempts, got.NextAt, "timeout", job.NextAt)
+		}
+	}
+}
+
+func TestPushAll(t *testing.T) {
+	q := newTestQueue(t)
+	bad := Message{AlertID: "b", Text: "cannot be stored"}
+	bad.Alert.Data.Payload = komodo.Map{"name": komodo.PayloadItem("{")}
+
+	if _, err := q.PushAll([]Message{{AlertID: "a", Text: "hello"}, bad}); err == nil {
+		t.Fatal("PushAll returned nil error for a message which cannot be stored")
+	}
+	if q.Len() != 0 {
+		t.Errorf("%d jobs queued after a failed PushAll, want none", q.Len())
+	}
+	n := 0
+	q.db.ForEach(bucket, func(string, []byte) error { n++; return nil })
+	if n != 0 {
+		t.Errorf("%d jobs left in the store after a failed PushAll, want none", n)
+	}
+
+	jobs, err := q.PushAll([]Message{{AlertID: "a", Text: "hello"}, {AlertID: "c", Text: "world"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || q.Len() != 2 {
+		t.Errorf("PushAll returned %d jobs and queued %d, want 2", len(jobs), q.Len())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := &Queue{minBackoff: 3 * time.Second, maxBackoff: time.Minute}
+	for attempts, want := range map[int]time.Duration{
+		1:  3 * time.Second,
+		2:  6 * time.Second,
+		3:  12 * time.Second,
+		5:  48 * time.Second,
+		6:  time.Minute,
+		50: time.Minute,
+	} {
+		if got := q.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	cases := []struct {
+		name string
+		// the delivery context is cancelled too, like at the shutdown
+		// deadline
+		abort bool
+	}{
+		{"stop", false},
+		{"stop and abort", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			q := newTestQueue(t)
+			job, err := q.Push(Message{AlertID: "a", Text: "hello"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.Push(Message{AlertID: "b", Text: "not taken"})
+
+			started := make(chan struct{})
+			h := func(ctx context.Context, j *Job) error {
+				if j != job {
+					t.Errorf("job %s is taken after stopping", j.Message.AlertID)
+					return nil
+				}
+				close(started)
+				if c.abort {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				time.Sleep(10 * time.Millisecond)
+				return ctx.Err()
+			}
+
+			ctx, abort := context.WithCancel(context.Background())
+			defer abort()
+			stop, stopRun := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				q.Run(ctx, stop, h)
+			}()
+
+			<-started
+			stopRun()
+			if c.abort {
+				abort()
+			}
+			<-done
+
+			attempted, result := q.Wait(context.Background(), job)
+			if !attempted {
+				t.Fatal("Run returned before the delivery in progress finished")
+			}
+			if c.abort != (result != nil) {
+				t.Errorf("delivery returned %v, want it aborted %v", result, c.abort)
+			}
+			want := 1
+			if c.abort {
+				want = 2
+			}
+			if q.Len() != want {
+				t.Errorf("%d jobs left, want %d", q.Len(), want)
+			}
+		})
+	}
+}
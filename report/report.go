@@ -0,0 +1,251 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package report posts scheduled summaries of alert activity, built from
+// the alert history.
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/alerter"
+	"github.com/raohwork/komodo-tg-alerter/history"
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/tmpl"
+	"github.com/rs/zerolog/log"
+)
+
+// topServers is how many servers are listed in Report.TopServers.
+const topServers = 5
+
+type Config struct {
+	Name string `mapstructure:"name"`
+	// cron expression in general.timezone, see ParseSchedule
+	Schedule string `mapstructure:"schedule"`
+	// how far back the report looks; since the previous run if 0
+	Period time.Duration       `mapstructure:"period"`
+	Chats  []route.Destination `mapstructure:"chats"`
+}
+
+// Validate checks a report loaded from configuration.
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	s, err := ParseSchedule(c.Schedule)
+	if err != nil {
+		return fmt.Errorf("report %s: %w", c.Name, err)
+	}
+	if s.Next(time.Now()).IsZero() {
+		return fmt.Errorf("report %s: schedule %q never matches", c.Name, c.Schedule)
+	}
+	if c.Period < 0 {
+		return fmt.Errorf("report %s: period must not be negative", c.Name)
+	}
+	if len(c.Chats) == 0 {
+		return fmt.Errorf("report %s: no chats", c.Name)
+	}
+	return nil
+}
+
+// Validate checks reports loaded from configuration.
+func Validate(reports []Config) error {
+	names := map[string]bool{}
+	for _, c := range reports {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+		if names[c.Name] {
+			return fmt.Errorf("report %s: duplicated name", c.Name)
+		}
+		names[c.Name] = true
+	}
+	return nil
+}
+
+// Reporter sends reports on schedule.
+type Reporter struct {
+	Alerter *alerter.Alerter
+	History *history.History
+	Reports []Config
+	TZ      *time.Location
+}
+
+// Run sends each report on its schedule until ctx is done.
+func (r *Reporter) Run(ctx context.Context) {
+	for _, c := range r.Reports {
+		go r.run(ctx, c)
+	}
+}
+
+func (r *Reporter) run(ctx context.Context, c Config) {
+	l := log.With().Str("report", c.Name).Logger()
+	s, err := ParseSchedule(c.Schedule)
+	if err != nil {
+		l.Error().Err(err).Msg("report disabled")
+		return
+	}
+
+	var prev time.Time
+	for {
+		now := time.Now().In(r.TZ)
+		at := s.Next(now)
+		if at.IsZero() {
+			l.Error().Str("schedule", c.Schedule).Msg("schedule never matches, report disabled")
+			return
+		}
+		l.Debug().Time("at", at).Msg("next report scheduled")
+
+		timer := time.NewTimer(at.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		start := prev
+		switch {
+		case c.Period > 0:
+			start = at.Add(-c.Period)
+		case start.IsZero():
+			// the first run after starting, assume runs are evenly spaced
+			start = at.Add(-s.Next(at).Sub(at))
+		}
+		prev = at
+		data, err := r.Build(c.Name, start, at)
+		if err == nil {
+			err = r.Alerter.QueueReport(c.Chats, data)
+		}
+		if err != nil {
+			l.Error().Err(err).Msg("failed to send report")
+			continue
+		}
+		l.Info().Int("alerts", data.Total).Msg("report queued")
+	}
+}
+
+// serverName returns the name of the server a is about, or an empty string.
+func serverName(a *komodo.AlertInfo) string {
+	if n := a.Data.Payload.Get("server_name").Str(); n != "" {
+		return n
+	}
+	if strings.EqualFold(a.Target.Type, "Server") {
+		return a.Data.Payload.Get("name").Str()
+	}
+	return ""
+}
+
+// isImageUpdate reports whether a tells a newer image is available.
+func isImageUpdate(a *komodo.AlertInfo) bool {
+	return strings.HasSuffix(a.Data.Type, "ImageUpdateAvailable")
+}
+
+// sortCounts converts counts to tmpl.Count, most first, keeping at most limit
+// of them if limit is positive.
+func sortCounts(counts map[string]int, limit int) []tmpl.Count {
+	ret := make([]tmpl.Count, 0, len(counts))
+	for name, n := range counts {
+		ret = append(ret, tmpl.Count{Name: name, Count: n})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Count != ret[j].Count {
+			return ret[i].Count > ret[j].Count
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	if limit > 0 && len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret
+}
+
+// Build summarizes alerts received between start and end. Open alerts and
+// pending image updates are the current ones, whenever they are received.
+func (r *Reporter) Build(name string, start, end time.Time) (*tmpl.Report, error) {
+	entries, err := r.History.Since(time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	open, err := r.Alerter.OpenAlerts()
+	if err != nil {
+		return nil, fmt.Errorf("read open alerts: %w", err)
+	}
+
+	ret := &tmpl.Report{Name: name, Start: start, End: end}
+	byLevel := map[string]int{}
+	byType := map[string]int{}
+	servers := map[string]int{}
+	var resolveTime time.Duration
+	// latest alert about each condition
+	latest := map[string]*komodo.AlertInfo{}
+	var keys []string
+	for i := range entries {
+		e := &entries[i]
+		a := &e.Alert
+		if isImageUpdate(a) {
+			if _, ok := latest[a.Key()]; !ok {
+				keys = append(keys, a.Key())
+			}
+			latest[a.Key()] = a
+		}
+		if e.ReceivedAt.Before(start) || !e.ReceivedAt.Before(end) {
+			continue
+		}
+
+		ret.Total++
+		byLevel[a.Level]++
+		byType[a.Data.Type]++
+		if s := serverName(a); s != "" {
+			servers[s]++
+		}
+		if a.Resolved && a.Timestamp > 0 && a.ResolveTimestamp >= a.Timestamp {
+			ret.Resolved++
+			resolveTime += a.Duration()
+		}
+	}
+	ret.ByLevel = sortCounts(byLevel, 0)
+	ret.ByType = sortCounts(byType, 0)
+	ret.TopServers = sortCounts(servers, topServers)
+	if ret.Resolved > 0 {
+		ret.MeanTimeToResolve = resolveTime / time.Duration(ret.Resolved)
+	}
+
+	for _, o := range open {
+		ret.Open = append(ret.Open, tmpl.ReportAlert{
+			Type:     o.Type,
+			Level:    o.Level,
+			Target:   o.Target,
+			Name:     o.Name,
+			IssuedAt: o.IssuedAt,
+			Age:      end.Sub(o.IssuedAt),
+			AckedBy:  o.AckedBy,
+		})
+	}
+	for _, key := range keys {
+		if a := latest[key]; !a.Resolved {
+			ret.ImageUpdates = append(ret.ImageUpdates, a)
+		}
+	}
+	return ret, nil
+}
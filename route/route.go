@@ -0,0 +1,252 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package route decides which chats an alert is delivered to.
+package route
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+)
+
+// DefaultName is the name of the route used when no other route matches.
+const DefaultName = "default"
+
+// Match selects alerts. Every non-empty field must match; a field matches if
+// any of its patterns matches. Patterns use path.Match syntax, so "prod-*"
+// matches "prod-1".
+type Match struct {
+	Level      []string `mapstructure:"level" json:"level,omitempty"`
+	Type       []string `mapstructure:"type" json:"type,omitempty"`
+	TargetType []string `mapstructure:"target_type" json:"target_type,omitempty"`
+	TargetID   []string `mapstructure:"target_id" json:"target_id,omitempty"`
+	// keys are payload fields like server_name
+	Payload map[string][]string `mapstructure:"payload" json:"payload,omitempty"`
+}
+
+func matchAny(patterns []string, v string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, v); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAnyFold is matchAny ignoring case, for values like levels which
+// Komodo sends in upper case but people write in lower case.
+func matchAnyFold(patterns []string, v string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	v = strings.ToLower(v)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), v); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether a is selected by m. An empty Match selects all
+// alerts. Levels and target types are compared case-insensitively.
+func (m *Match) Matches(a *komodo.AlertInfo) bool {
+	if !matchAnyFold(m.Level, a.Level) ||
+		!matchAny(m.Type, a.Data.Type) ||
+		!matchAnyFold(m.TargetType, a.Target.Type) ||
+		!matchAny(m.TargetID, a.Target.ID) {
+		return false
+	}
+
+	for key, patterns := range m.Payload {
+		if !a.Data.Payload.Has(key) {
+			return false
+		}
+		if !matchAny(patterns, a.Data.Payload.Get(key).String()) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmpty reports whether m selects all alerts.
+func (m *Match) IsEmpty() bool {
+	return len(m.Level) == 0 && len(m.Type) == 0 && len(m.TargetType) == 0 &&
+		len(m.TargetID) == 0 && len(m.Payload) == 0
+}
+
+// ParseMatch parses matchers like "type=ServerCpu" or
+// "server_name=prod-*,staging-*". Keys other than level, type, target_type and
+// target_id are payload fields.
+func ParseMatch(args []string) (Match, error) {
+	var m Match
+	for _, arg := range args {
+		key, val, ok := strings.Cut(arg, "=")
+		if !ok || key == "" || val == "" {
+			return m, fmt.Errorf("invalid matcher %q, expected key=pattern", arg)
+		}
+		patterns := strings.Split(val, ",")
+		switch key {
+		case "level":
+			m.Level = append(m.Level, patterns...)
+		case "type":
+			m.Type = append(m.Type, patterns...)
+		case "target_type":
+			m.TargetType = append(m.TargetType, patterns...)
+		case "target_id":
+			m.TargetID = append(m.TargetID, patterns...)
+		default:
+			if m.Payload == nil {
+				m.Payload = map[string][]string{}
+			}
+			m.Payload[key] = append(m.Payload[key], patterns...)
+		}
+	}
+	return m, m.Validate()
+}
+
+// String formats m in the syntax of ParseMatch.
+func (m *Match) String() string {
+	var parts []string
+	add := func(key string, patterns []string) {
+		if len(patterns) > 0 {
+			parts = append(parts, key+"="+strings.Join(patterns, ","))
+		}
+	}
+	add("level", m.Level)
+	add("type", m.Type)
+	add("target_type", m.TargetType)
+	add("target_id", m.TargetID)
+	keys := make([]string, 0, len(m.Payload))
+	for k := range m.Payload {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, m.Payload[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Validate checks that every pattern is well-formed.
+func (m *Match) Validate() error {
+	all := [][]string{m.Level, m.Type, m.TargetType, m.TargetID}
+	for _, patterns := range m.Payload {
+		all = append(all, patterns)
+	}
+	for _, patterns := range all {
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Destination is a chat to deliver alerts to.
+type Destination struct {
+	// name of the notifier, Telegram if empty
+	Notifier string `mapstructure:"notifier"`
+	// Telegram chat ID, unused by other notifiers
+	Chat int64 `mapstructure:"chat"`
+	// message_thread_id of a forum topic, 0 for the general topic
+	Thread int `mapstructure:"thread"`
+}
+
+type Route struct {
+	Name  string        `mapstructure:"name"`
+	Match Match         `mapstructure:"match"`
+	Chats []Destination `mapstructure:"chats"`
+	// keep looking for other matching routes after this one
+	Continue bool `mapstructure:"continue"`
+}
+
+// Validate checks routes loaded from configuration.
+func Validate(routes []Route) error {
+	for i, r := range routes {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if len(r.Chats) == 0 {
+			return fmt.Errorf("route %s: no chats", name)
+		}
+		if err := r.Match.Validate(); err != nil {
+			return fmt.Errorf("route %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ErrNoRoute is returned by Resolve if an alert should be sent nowhere.
+var ErrNoRoute = errors.New("no route matched")
+
+// Delivery is a destination selected by a route.
+type Delivery struct {
+	Route string
+	Destination
+}
+
+// Resolve walks routes in order and returns the destinations of matched
+// routes, stopping at the first matched route without Continue. If nothing
+// matches, fallback is used as the default route.
+func Resolve(routes []Route, fallback []Destination, a *komodo.AlertInfo) ([]Delivery, error) {
+	var ret []Delivery
+	seen := map[Destination]bool{}
+	add := func(name string, dests []Destination) {
+		for _, d := range dests {
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			ret = append(ret, Delivery{Route: name, Destination: d})
+		}
+	}
+
+	matched := false
+	for i, r := range routes {
+		if !r.Match.Matches(a) {
+			continue
+		}
+		matched = true
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		add(name, r.Chats)
+		if !r.Continue {
+			break
+		}
+	}
+	if !matched {
+		add(DefaultName, fallback)
+	}
+
+	if len(ret) == 0 {
+		return nil, ErrNoRoute
+	}
+	return ret, nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package silence
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/route"
+)
+
+// CreateRequest is the body of POST /api/silences. Either End or Duration
+// (like "2h") is required.
+type CreateRequest struct {
+	Match     route.Match `json:"match"`
+	Start     time.Time   `json:"start,omitzero"`
+	End       time.Time   `json:"end,omitzero"`
+	Duration  string      `json:"duration,omitempty"`
+	Comment   string      `json:"comment,omitempty"`
+	CreatedBy string      `json:"created_by,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
+
+// Register adds the admin API to mux:
+//
+//   - GET /api/silences lists silences
+//   - POST /api/silences creates a silence, see CreateRequest
+//   - DELETE /api/silences/{id} removes a silence
+func (m *Manager) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/silences", m.handleList)
+	mux.HandleFunc("POST /api/silences", m.handleCreate)
+	mux.HandleFunc("DELETE /api/silences/{id}", m.handleRemove)
+}
+
+func (m *Manager) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.List())
+}
+
+func (m *Manager) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s := Silence{
+		Match:     req.Match,
+		Start:     req.Start,
+		End:       req.End,
+		Comment:   req.Comment,
+		CreatedBy: req.CreatedBy,
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid duration"))
+			return
+		}
+		if s.Start.IsZero() {
+			s.Start = time.Now()
+		}
+		s.End = s.Start.Add(d)
+	}
+
+	ret, err := m.Add(s)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ret)
+}
+
+func (m *Manager) handleRemove(w http.ResponseWriter, r *http.Request) {
+	err := m.Remove(r.PathValue("id"))
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrStatic):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,300 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package silence mutes alerts during maintenance.
+//
+// A silence selects alerts with a route.Match and is active between its start
+// and end time. Silences come from configuration (static) or are created at
+// runtime through the admin API or bot commands; the latter are kept in the
+// data store and removed once they end.
+package silence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/store"
+	"github.com/rs/zerolog/log"
+)
+
+const bucket = "silences"
+
+type Silence struct {
+	ID    string      `json:"id"`
+	Match route.Match `json:"match"`
+	Start time.Time   `json:"start"`
+	// zero for silences which never end, only allowed in configuration
+	End       time.Time `json:"end,omitzero"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	// number of alerts muted by this silence
+	Hits int `json:"hits"`
+	// defined in configuration, cannot be removed at runtime
+	Static bool `json:"static,omitempty"`
+}
+
+// Active reports whether s mutes alerts at t.
+func (s *Silence) Active(t time.Time) bool {
+	return !t.Before(s.Start) && !s.Expired(t)
+}
+
+// Expired reports whether s has ended at t.
+func (s *Silence) Expired(t time.Time) bool {
+	return !s.End.IsZero() && !t.Before(s.End)
+}
+
+func (s *Silence) validate() error {
+	if err := s.validateMatch(); err != nil {
+		return err
+	}
+	if !s.End.IsZero() && !s.End.After(s.Start) {
+		return errors.New("end must be after start")
+	}
+	return nil
+}
+
+func (s *Silence) validateMatch() error {
+	if s.Match.IsEmpty() {
+		return errors.New("matcher is required")
+	}
+	return s.Match.Validate()
+}
+
+// Config is a silence defined in configuration. Times are RFC3339; an empty
+// Start means now and an empty End means forever.
+type Config struct {
+	Name    string      `mapstructure:"name"`
+	Match   route.Match `mapstructure:"match"`
+	Start   string      `mapstructure:"start"`
+	End     string      `mapstructure:"end"`
+	Comment string      `mapstructure:"comment"`
+}
+
+// Silence converts c to a static silence.
+func (c *Config) Silence() (*Silence, error) {
+	if c.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	ret := &Silence{
+		ID:      c.Name,
+		Match:   c.Match,
+		Start:   time.Now(),
+		Comment: c.Comment,
+		Static:  true,
+	}
+	var err error
+	if c.Start != "" {
+		if ret.Start, err = time.Parse(time.RFC3339, c.Start); err != nil {
+			return nil, fmt.Errorf("silence %s: start: %w", c.Name, err)
+		}
+	}
+	if c.End != "" {
+		if ret.End, err = time.Parse(time.RFC3339, c.End); err != nil {
+			return nil, fmt.Errorf("silence %s: end: %w", c.Name, err)
+		}
+	}
+	check := ret.validate
+	if c.Start == "" && ret.Expired(ret.Start) {
+		// only the end is given and it has passed: the silence is over,
+		// which New drops rather than refusing the configuration
+		ret.Start = ret.End
+		check = ret.validateMatch
+	}
+	if err = check(); err != nil {
+		return nil, fmt.Errorf("silence %s: %w", c.Name, err)
+	}
+	return ret, nil
+}
+
+// Errors returned by Manager.
+var (
+	ErrNotFound = errors.New("silence not found")
+	ErrStatic   = errors.New("silence is defined in configuration")
+)
+
+// Manager keeps silences and decides whether an alert is muted.
+type Manager struct {
+	db *store.DB
+
+	mu   sync.Mutex
+	list []*Silence
+}
+
+// New creates a Manager with static silences and those saved in db.
+func New(db *store.DB, static []Config) (*Manager, error) {
+	m := &Manager{db: db}
+	seen := map[string]bool{}
+	now := time.Now()
+	for _, c := range static {
+		s, err := c.Silence()
+		if err != nil {
+			return nil, err
+		}
+		if seen[s.ID] {
+			return nil, fmt.Errorf("silence %s: duplicated name", s.ID)
+		}
+		seen[s.ID] = true
+		if s.Expired(now) {
+			log.Warn().Str("silence", s.ID).Time("end", s.End).
+				Msg("silence in configuration has ended, skipping it")
+			continue
+		}
+		m.list = append(m.list, s)
+	}
+
+	err := db.ForEach(bucket, func(key string, val []byte) error {
+		var s Silence
+		if err := json.Unmarshal(val, &s); err != nil {
+			log.Warn().Err(err).Str("silence", key).Msg("skipping corrupted silence")
+			return nil
+		}
+		m.list = append(m.list, &s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(now)
+	return m, nil
+}
+
+// expire removes silences which have ended. Caller must hold mu.
+func (m *Manager) expire(now time.Time) {
+	m.list = slices.DeleteFunc(m.list, func(s *Silence) bool {
+		if !s.Expired(now) {
+			return false
+		}
+		log.Info().Str("silence", s.ID).Int("hits", s.Hits).Msg("silence expired")
+		if !s.Static {
+			if err := m.db.Delete(bucket, s.ID); err != nil {
+				log.Error().Err(err).Str("silence", s.ID).Msg("failed to delete expired silence")
+			}
+		}
+		return true
+	})
+}
+
+func (m *Manager) save(s *Silence) error {
+	if s.Static {
+		return nil
+	}
+	return m.db.Put(bucket, s.ID, s)
+}
+
+// List returns silences which are active or not yet started, ordered by
+// start time.
+func (m *Manager) List() []Silence {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire(time.Now())
+
+	ret := make([]Silence, 0, len(m.list))
+	for _, s := range m.list {
+		ret = append(ret, *s)
+	}
+	slices.SortStableFunc(ret, func(a, b Silence) int {
+		return a.Start.Compare(b.Start)
+	})
+	return ret
+}
+
+// Add validates and saves s, assigning an ID and start time if missing.
+func (m *Manager) Add(s Silence) (*Silence, error) {
+	now := time.Now()
+	if s.Start.IsZero() {
+		s.Start = now
+	}
+	if s.End.IsZero() {
+		return nil, errors.New("end is required")
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	if s.Expired(now) {
+		return nil, errors.New("silence has already ended")
+	}
+	s.ID = fmt.Sprintf("%x", now.UnixNano())
+	s.Hits = 0
+	s.Static = false
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.save(&s); err != nil {
+		return nil, err
+	}
+	m.list = append(m.list, &s)
+	log.Info().
+		Str("silence", s.ID).
+		Str("match", s.Match.String()).
+		Time("start", s.Start).
+		Time("end", s.End).
+		Str("by", s.CreatedBy).
+		Msg("silence created")
+
+	ret := s
+	return &ret, nil
+}
+
+// Remove deletes a silence created at runtime.
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	idx := slices.IndexFunc(m.list, func(s *Silence) bool { return s.ID == id })
+	if idx < 0 {
+		return ErrNotFound
+	}
+	s := m.list[idx]
+	if s.Static {
+		return ErrStatic
+	}
+	if err := m.db.Delete(bucket, id); err != nil {
+		return err
+	}
+	m.list = slices.Delete(m.list, idx, idx+1)
+	log.Info().Str("silence", id).Int("hits", s.Hits).Msg("silence removed")
+	return nil
+}
+
+// Match returns the first active silence which mutes a, and counts the hit.
+func (m *Manager) Match(a *komodo.AlertInfo) (Silence, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.expire(now)
+	for _, s := range m.list {
+		if !s.Active(now) || !s.Match.Matches(a) {
+			continue
+		}
+		s.Hits++
+		if err := m.save(s); err != nil {
+			log.Error().Err(err).Str("silence", s.ID).Msg("failed to save silence")
+		}
+		return *s, true
+	}
+	return Silence{}, false
+}
@@ -0,0 +1,79 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package silence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/route"
+	"github.com/raohwork/komodo-tg-alerter/store"
+)
+
+func TestConfigSilence(t *testing.T) {
+	match := route.Match{Type: []string{"ServerCpu"}}
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	earlier := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	cases := []struct {
+		name    string
+		c       Config
+		ok      bool
+		expired bool
+	}{
+		{"forever", Config{Name: "a", Match: match}, true, false},
+		{"until future", Config{Name: "a", Match: match, End: future}, true, false},
+		{"until past", Config{Name: "a", Match: match, End: past}, true, true},
+		{"past range", Config{Name: "a", Match: match, Start: earlier, End: past}, true, true},
+		{"end before start", Config{Name: "a", Match: match, Start: past, End: earlier}, false, false},
+		{"end at start", Config{Name: "a", Match: match, Start: past, End: past}, false, false},
+		{"ended without matcher", Config{Name: "a", End: past}, false, false},
+		{"without name", Config{Match: match}, false, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := c.c.Silence()
+			if (err == nil) != c.ok {
+				t.Fatalf("got error %v, want ok %v", err, c.ok)
+			}
+			if err == nil && s.Expired(time.Now()) != c.expired {
+				t.Errorf("expired is %v, want %v", !c.expired, c.expired)
+			}
+		})
+	}
+}
+
+func TestNewSkipsEndedStatic(t *testing.T) {
+	db, err := store.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := route.Match{Type: []string{"ServerCpu"}}
+	m, err := New(db, []Config{
+		{Name: "ended", Match: match, End: time.Now().Add(-time.Hour).Format(time.RFC3339)},
+		{Name: "active", Match: match},
+	})
+	if err != nil {
+		t.Fatalf("ended static silence is rejected: %v", err)
+	}
+	list := m.List()
+	if len(list) != 1 || list[0].ID != "active" {
+		t.Errorf("silences are %v, want only the active one", list)
+	}
+}
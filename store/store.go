@@ -0,0 +1,194 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package store provides a tiny key-value store to keep state across restarts.
+//
+// Values are stored as JSON in named buckets. When no data directory is
+// configured, everything is kept in memory and lost on exit.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// FileName is the name of the database file created in the data directory.
+const FileName = "kta.db"
+
+type DB struct {
+	bolt *bolt.DB
+
+	mu  sync.RWMutex
+	mem map[string]map[string][]byte
+}
+
+// Open opens (or creates) the database in dir. If dir is empty, an in-memory
+// database is returned.
+func Open(dir string) (*DB, error) {
+	if dir == "" {
+		return &DB{mem: map[string]map[string][]byte{}}, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, FileName), 0600, &bolt.Options{
+		Timeout: 3 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	return &DB{bolt: db}, nil
+}
+
+// Persistent reports whether data is written to disk.
+func (d *DB) Persistent() bool {
+	return d.bolt != nil
+}
+
+func (d *DB) Close() error {
+	if d.bolt == nil {
+		return nil
+	}
+	return d.bolt.Close()
+}
+
+// Ping checks that the database is writable.
+func (d *DB) Ping() error {
+	return d.Put("health", "ping", time.Now())
+}
+
+// Put stores v as JSON under key in bucket.
+func (d *DB) Put(bucket, key string, v any) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode %s/%s: %w", bucket, key, err)
+	}
+
+	if d.bolt == nil {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		b, ok := d.mem[bucket]
+		if !ok {
+			b = map[string][]byte{}
+			d.mem[bucket] = b
+		}
+		b[key] = buf
+		return nil
+	}
+
+	return d.bolt.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), buf)
+	})
+}
+
+// Get loads the value under key in bucket into v. It reports whether the key
+// exists.
+func (d *DB) Get(bucket, key string, v any) (bool, error) {
+	var buf []byte
+	if d.bolt == nil {
+		d.mu.RLock()
+		buf = d.mem[bucket][key]
+		d.mu.RUnlock()
+	} else {
+		err := d.bolt.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(bucket))
+			if b == nil {
+				return nil
+			}
+			if data := b.Get([]byte(key)); data != nil {
+				buf = append([]byte(nil), data...)
+			}
+			return nil
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if buf == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(buf, v); err != nil {
+		return true, fmt.Errorf("decode %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Delete removes key from bucket. Deleting a missing key is not an error.
+func (d *DB) Delete(bucket, key string) error {
+	if d.bolt == nil {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		delete(d.mem[bucket], key)
+		return nil
+	}
+
+	return d.bolt.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// ForEach calls fn for every entry in bucket in key order. Iteration stops at
+// the first error returned by fn. fn must not modify the database.
+func (d *DB) ForEach(bucket string, fn func(key string, val []byte) error) error {
+	if d.bolt == nil {
+		d.mu.RLock()
+		b := d.mem[bucket]
+		keys := make([]string, 0, len(b))
+		vals := make(map[string][]byte, len(b))
+		for k, v := range b {
+			keys = append(keys, k)
+			vals[k] = v
+		}
+		d.mu.RUnlock()
+
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := fn(k, vals[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return d.bolt.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return fn(string(k), v)
+		})
+	})
+}
@@ -0,0 +1,262 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package tmpl
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Entity is a formatted part of a message, like MessageEntity of the
+// Telegram Bot API.
+type Entity struct {
+	// bold, italic, underline, strikethrough, spoiler, code, pre, text_link
+	// or blockquote
+	Type string
+	// position in the plain text, in UTF-16 code units like Telegram
+	Offset int
+	Length int
+	// target of text_link
+	URL string
+	// language of pre
+	Language string
+}
+
+// Entities parses text written in f into the plain text and the entities
+// Telegram shows. Invalid markup is reported like Check does. Text in
+// formats other than Telegram ones is returned as is, without entities.
+func (f Format) Entities(text string) (string, []Entity, error) {
+	if err := f.Check(text); err != nil {
+		return "", nil, err
+	}
+	var p entityParser
+	switch f {
+	case FormatMarkdownV2:
+		p.markdownV2(text)
+	case FormatHTML:
+		p.html(text)
+	default:
+		return text, nil, nil
+	}
+	sort.SliceStable(p.entities, func(i, j int) bool {
+		a, b := p.entities[i], p.entities[j]
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return a.Length > b.Length
+	})
+	return p.plain.String(), p.entities, nil
+}
+
+// entityParser collects plain text and entities of valid markup.
+type entityParser struct {
+	plain    strings.Builder
+	offset   int
+	entities []Entity
+}
+
+func (p *entityParser) write(s string) {
+	p.plain.WriteString(s)
+	for _, r := range s {
+		p.offset += utf16.RuneLen(r)
+	}
+}
+
+// add adds an entity from start to the current offset, if it is not empty.
+func (p *entityParser) add(e Entity, start int) {
+	if p.offset > start {
+		e.Offset = start
+		e.Length = p.offset - start
+		p.entities = append(p.entities, e)
+	}
+}
+
+// unescapeMarkdownV2 removes backslashes escaping characters.
+func unescapeMarkdownV2(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+var markdownV2Entities = map[string]string{
+	"*":  "bold",
+	"_":  "italic",
+	"__": "underline",
+	"~":  "strikethrough",
+	"||": "spoiler",
+}
+
+func (p *entityParser) markdownV2(text string) {
+	type open struct {
+		marker string
+		start  int
+	}
+	var stack []open
+	quote := -1
+	lineStart := true
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		atLineStart := lineStart
+		lineStart = c == '\n'
+
+		if c == '\n' && quote >= 0 && !strings.HasPrefix(text[i+1:], ">") {
+			p.add(Entity{Type: "blockquote"}, quote)
+			quote = -1
+		}
+
+		switch {
+		case c == '\\':
+			i++
+			_, n := utf8.DecodeRuneInString(text[i:])
+			p.write(text[i : i+n])
+			i += n - 1
+		case c == '`':
+			fence := "`"
+			if strings.HasPrefix(text[i:], "```") {
+				fence = "```"
+			}
+			end := closing(text, i+len(fence), fence)
+			body := text[i+len(fence) : end]
+			e := Entity{Type: "code"}
+			if fence == "```" {
+				e.Type = "pre"
+				if lang, rest, ok := strings.Cut(body, "\n"); ok && !strings.ContainsAny(lang, " \\") {
+					e.Language = lang
+					body = rest
+				}
+			}
+			start := p.offset
+			p.write(unescapeMarkdownV2(body))
+			p.add(e, start)
+			i = end + len(fence) - 1
+		case c == '*' || c == '~' || c == '_' || c == '|':
+			marker := string(c)
+			if (c == '_' || c == '|') && strings.HasPrefix(text[i:], marker+marker) {
+				marker += marker
+			}
+			if n := len(stack) - 1; n >= 0 && stack[n].marker == marker {
+				p.add(Entity{Type: markdownV2Entities[marker]}, stack[n].start)
+				stack = stack[:n]
+			} else {
+				stack = append(stack, open{marker, p.offset})
+			}
+			i += len(marker) - 1
+		case c == '[':
+			stack = append(stack, open{"[", p.offset})
+		case c == ']':
+			end := closing(text, i+2, ")")
+			n := len(stack) - 1
+			p.add(Entity{Type: "text_link", URL: unescapeMarkdownV2(text[i+2 : end])}, stack[n].start)
+			stack = stack[:n]
+			i = end
+		case c == '>' && atLineStart:
+			if quote < 0 {
+				quote = p.offset
+			}
+		default:
+			_, n := utf8.DecodeRuneInString(text[i:])
+			p.write(text[i : i+n])
+			i += n - 1
+		}
+	}
+	if quote >= 0 {
+		p.add(Entity{Type: "blockquote"}, quote)
+	}
+}
+
+var htmlEntities = map[string]string{
+	"b":          "bold",
+	"strong":     "bold",
+	"i":          "italic",
+	"em":         "italic",
+	"u":          "underline",
+	"ins":        "underline",
+	"s":          "strikethrough",
+	"strike":     "strikethrough",
+	"del":        "strikethrough",
+	"tg-spoiler": "spoiler",
+	"span":       "spoiler",
+	"a":          "text_link",
+	"code":       "code",
+	"pre":        "pre",
+	"blockquote": "blockquote",
+}
+
+var (
+	htmlHref  = regexp.MustCompile(`href\s*=\s*"([^"]*)"`)
+	htmlClass = regexp.MustCompile(`class\s*=\s*"([^"]*)"`)
+)
+
+func (p *entityParser) html(text string) {
+	type open struct {
+		e     Entity
+		start int
+	}
+	var stack []open
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '<':
+			m := htmlTag.FindStringSubmatch(text[i:])
+			name := strings.ToLower(m[2])
+			i += len(m[0]) - 1
+			if m[1] == "" {
+				e := Entity{Type: htmlEntities[name]}
+				if h := htmlHref.FindStringSubmatch(m[3]); name == "a" && h != nil {
+					e.URL = html.UnescapeString(h[1])
+				}
+				if c := htmlClass.FindStringSubmatch(m[3]); name == "code" && c != nil {
+					e.Language, _ = strings.CutPrefix(c[1], "language-")
+				}
+				stack = append(stack, open{e, p.offset})
+				continue
+			}
+
+			n := len(stack) - 1
+			o := stack[n]
+			stack = stack[:n]
+			if o.e.Type == "code" && n > 0 && stack[n-1].e.Type == "pre" {
+				// <pre><code class="language-x"> is a pre block in x
+				stack[n-1].e.Language = o.e.Language
+				continue
+			}
+			if o.e.Type == "code" {
+				o.e.Language = ""
+			}
+			if o.e.Type != "" {
+				p.add(o.e, o.start)
+			}
+		case '&':
+			m := htmlEntity.FindString(text[i:])
+			p.write(html.UnescapeString(m))
+			i += len(m) - 1
+		default:
+			_, n := utf8.DecodeRuneInString(text[i:])
+			p.write(text[i : i+n])
+			i += n - 1
+		}
+	}
+}
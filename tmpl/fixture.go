@@ -0,0 +1,176 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package tmpl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/raohwork/komodo-tg-alerter/komodo"
+)
+
+// FixtureDir is the directory in a template directory holding fixtures,
+// alerts in FixtureDir/<AlertType>/*.json with the expected output of each
+// in a .golden file next to it.
+const FixtureDir = "testdata"
+
+// loadFixture decodes the alert in a fixture of typ. A fixture is an alert
+// like Komodo sends, or just its payload if there is no "data".
+func loadFixture(buf []byte, typ string) (*komodo.AlertInfo, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &fields); err != nil {
+		return nil, err
+	}
+	var ret komodo.AlertInfo
+	if _, ok := fields["data"]; ok {
+		if err := json.Unmarshal(buf, &ret); err != nil {
+			return nil, err
+		}
+	} else {
+		ret.Level = "WARNING"
+		if err := json.Unmarshal(buf, &ret.Data.Payload); err != nil {
+			return nil, err
+		}
+	}
+	ret.Data.Type = typ
+	if ret.Resolved && ret.ResolveTimestamp == 0 {
+		// the duration would be measured up to now, changing on every run
+		return nil, errors.New("a resolved fixture needs resolve_at, or its output changes on every run")
+	}
+	return &ret, nil
+}
+
+// LintFixtures renders the fixtures of templates in dir and compares them
+// with the golden files, printing the differences. With update, golden files
+// are written with the output instead. It returns an error if any fixture
+// fails to render or does not match.
+func LintFixtures(dir string, tz *time.Location, format Format, update bool) error {
+	fsys := os.DirFS(dir)
+	files, err := fs.Glob(fsys, FixtureDir+"/*/*.json")
+	if err != nil || len(files) == 0 {
+		return err
+	}
+
+	renderer := NewRenderer(fsys, tz, format)
+	var failed int
+	for _, name := range files {
+		fmt.Printf("📝 Checking fixture %s...\n", name)
+		golden := strings.TrimSuffix(name, ".json") + ".golden"
+		if err := checkFixture(renderer, fsys, dir, name, golden, update); err != nil {
+			fmt.Printf("❌ Error: %v\n\n", err)
+			failed++
+			continue
+		}
+		if update {
+			fmt.Printf("✅ Updated %s\n\n", golden)
+		} else {
+			fmt.Print("✅ Matches golden file\n\n")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fixtures failed", failed, len(files))
+	}
+	fmt.Printf("✅ All %d fixtures match!\n", len(files))
+	return nil
+}
+
+// checkFixture renders the fixture name and compares the output with the
+// golden file, or writes it to the golden file if update is set.
+func checkFixture(r *Renderer, fsys fs.FS, dir, name, golden string, update bool) error {
+	buf, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	data, err := loadFixture(buf, path.Base(path.Dir(name)))
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", name, err)
+	}
+	text, err := r.RenderMessage(data)
+	if err == nil {
+		err = r.Format(data.Data.Type).lint(text)
+	}
+	if err != nil {
+		return err
+	}
+	text = strings.TrimRight(text, "\n") + "\n"
+
+	if update {
+		return os.WriteFile(filepath.Join(dir, filepath.FromSlash(golden)), []byte(text), 0644)
+	}
+	want, err := fs.ReadFile(fsys, golden)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s does not exist, run with --update to create it", golden)
+	}
+	if err != nil {
+		return err
+	}
+	if string(want) != text {
+		diff := strings.TrimSuffix(diffLines(string(want), text), "\n")
+		return fmt.Errorf("output differs from %s:\n%s", golden, diff)
+	}
+	return nil
+}
+
+// diffLines returns the lines of a and b, those only in a prefixed with "- ",
+// those only in b with "+ " and common ones with two spaces.
+func diffLines(a, b string) string {
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ret strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ret.WriteString("  " + x[i] + "\n")
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			ret.WriteString("- " + x[i] + "\n")
+			i++
+		default:
+			ret.WriteString("+ " + y[j] + "\n")
+			j++
+		}
+	}
+	return ret.String()
+}
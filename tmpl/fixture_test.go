@@ -0,0 +1,122 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package tmpl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFixtureDir creates a template directory with a ServerCpu template and a
+// fixture of it, and returns the directory and the path of the golden file.
+func newFixtureDir(t *testing.T, golden string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeTemplate(t, dir, "ServerCpu.txt", "Server: {{ .Typed.Name }}\nCPU: {{ .Typed.Percentage }}%")
+	fixtures := filepath.Join(dir, FixtureDir, "ServerCpu")
+	if err := os.MkdirAll(fixtures, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTemplate(t, fixtures, "high.json", `{"name": "web-1", "percentage": 97.5}`)
+	path := filepath.Join(fixtures, "high.golden")
+	if golden != "" {
+		writeTemplate(t, fixtures, "high.golden", golden)
+	}
+	return dir, path
+}
+
+const highGolden = "Server: web-1\nCPU: 97.5%\n"
+
+func TestLintFixtures(t *testing.T) {
+	t.Run("match", func(t *testing.T) {
+		dir, _ := newFixtureDir(t, highGolden)
+		if err := LintFixtures(dir, time.UTC, FormatPlain, false); err != nil {
+			t.Errorf("matching golden file fails: %v", err)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		dir, _ := newFixtureDir(t, "Server: web-1\nCPU: 12%\n")
+		if err := LintFixtures(dir, time.UTC, FormatPlain, false); err == nil {
+			t.Error("mismatching golden file passes")
+		}
+
+		r := NewRendererFromPath(dir, time.UTC, FormatPlain)
+		err := checkFixture(r, os.DirFS(dir), dir, FixtureDir+"/ServerCpu/high.json", FixtureDir+"/ServerCpu/high.golden", false)
+		want := "output differs from testdata/ServerCpu/high.golden:\n  Server: web-1\n- CPU: 12%\n+ CPU: 97.5%"
+		if err == nil || err.Error() != want {
+			t.Errorf("got error %v, want %q", err, want)
+		}
+	})
+
+	t.Run("missing golden", func(t *testing.T) {
+		dir, _ := newFixtureDir(t, "")
+		if err := LintFixtures(dir, time.UTC, FormatPlain, false); err == nil {
+			t.Error("fixture without golden file passes")
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		dir, golden := newFixtureDir(t, "outdated\n")
+		if err := LintFixtures(dir, time.UTC, FormatPlain, true); err != nil {
+			t.Fatal(err)
+		}
+		buf, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != highGolden {
+			t.Errorf("golden file is %q after update, want %q", buf, highGolden)
+		}
+		if err := LintFixtures(dir, time.UTC, FormatPlain, false); err != nil {
+			t.Errorf("updated golden file fails: %v", err)
+		}
+	})
+}
+
+func TestLoadFixtureResolved(t *testing.T) {
+	base := `{"level": "WARNING", "resolved": true, "ts": 1767225600000, "data": {"type": "ServerCpu", "data": {"name": "web-1"}}`
+	if _, err := loadFixture([]byte(base+`}`), "ServerCpu"); err == nil || !strings.Contains(err.Error(), "resolve_at") {
+		t.Errorf("resolved fixture without resolve_at returned %v, want an error about resolve_at", err)
+	}
+	a, err := loadFixture([]byte(base+`, "resolve_at": 1767226320000}`), "ServerCpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := a.Duration(); d != 12*time.Minute {
+		t.Errorf("duration is %v, want 12m", d)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	for _, c := range []struct {
+		a, b, want string
+	}{
+		{"a\nb\n", "a\nb\n", "  a\n  b\n"},
+		{"a\nb\nc", "a\nc", "  a\n- b\n  c\n"},
+		{"a\nc", "a\nb\nc", "  a\n+ b\n  c\n"},
+		{"a\nb", "a\nx", "  a\n- b\n+ x\n"},
+	} {
+		if got := diffLines(c.a, c.b); got != c.want {
+			t.Errorf("diffLines(%q, %q) = %q, want %q", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package tmpl
+
+import (
+	"html"
+	"strings"
+
+	"github.com/go-telegram/bot"
+)
+
+// Format is the markup syntax a template is written in. It decides how the
+// escape helpers in templates work.
+type Format string
+
+const (
+	FormatMarkdownV2 Format = "markdownv2" // Telegram
+	FormatHTML       Format = "html"       // Telegram
+	FormatDiscord    Format = "discord"
+	FormatSlack      Format = "slack"
+	FormatPlain      Format = "plain"
+)
+
+var discordEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"`", "\\`",
+	"|", `\|`,
+	">", `\>`,
+)
+
+var slackEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+func noEscape(s string) string { return s }
+
+var escapers = map[Format]func(string) string{
+	FormatMarkdownV2: bot.EscapeMarkdown,
+	FormatHTML:       html.EscapeString,
+	FormatDiscord:    discordEscaper.Replace,
+	FormatSlack:      slackEscaper.Replace,
+	FormatPlain:      noEscape,
+}
+
+// Valid reports whether f is a known format.
+func (f Format) Valid() bool {
+	_, ok := escapers[f]
+	return ok
+}
+
+func (f Format) escape(s string) string {
+	if fn, ok := escapers[f]; ok {
+		return fn(s)
+	}
+	return s
+}
+
+var boldMarkers = map[Format][2]string{
+	FormatMarkdownV2: {"*", "*"},
+	FormatHTML:       {"<b>", "</b>"},
+	FormatDiscord:    {"**", "**"},
+	FormatSlack:      {"*", "*"},
+}
+
+// bold wraps s, which must be escaped already, in the bold markup of f.
+func (f Format) bold(s string) string {
+	m, ok := boldMarkers[f]
+	if !ok {
+		return s
+	}
+	return m[0] + s + m[1]
+}
@@ -18,416 +18,248 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package tmpl
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/fs"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/raohwork/komodo-tg-alerter/komodo"
 )
 
-// helper function to create PayloadItem from any value
-func payloadItem(v any) komodo.PayloadItem {
-	b, _ := json.Marshal(v)
-	return komodo.PayloadItem(b)
+// sample creates an open alert with payload p. level and targetType are
+// spelled as Komodo sends them, so samples are routed and linked like real
+// alerts.
+func sample(level, targetType, targetID string, p komodo.Payload) *komodo.AlertInfo {
+	return &komodo.AlertInfo{
+		Timestamp: time.Now().UnixMilli(),
+		Level:     level,
+		Target: komodo.AlertTarget{
+			ID:   targetID,
+			Type: targetType,
+		},
+		Data: komodo.NewAlertData(p),
+	}
 }
 
-// helper function to create Map
-func payloadMap(data map[string]any) komodo.Map {
-	m := make(komodo.Map)
-	for k, v := range data {
-		m[k] = payloadItem(v)
+// sampleAlerts provides example AlertInfo for each alert type
+var sampleAlerts = map[string]*komodo.AlertInfo{}
+
+func init() {
+	for _, a := range []*komodo.AlertInfo{
+		sample("OK", "Alerter", "alerter-1", komodo.Test{
+			Resource: komodo.Resource{ID: "alerter-1", Name: "Test Alert Example"},
+		}),
+		sample("WARNING", "Server", "server-1", komodo.ServerVersionMismatch{
+			Server:        komodo.Server{ID: "server-1", Name: "production-server", Region: "us-west-2"},
+			ServerVersion: "v1.2.3",
+			CoreVersion:   "v1.2.5",
+		}),
+		sample("CRITICAL", "Server", "server-2", komodo.ServerUnreachable{
+			Server: komodo.Server{ID: "server-2", Name: "backup-server", Region: "eu-central-1"},
+			Err:    &komodo.Serror{Error: "connection timeout after 30s"},
+		}),
+		sample("WARNING", "Server", "server-3", komodo.ServerCpu{
+			Server:     komodo.Server{ID: "server-3", Name: "api-server-1", Region: "us-east-1"},
+			Percentage: 85.5,
+		}),
+		sample("WARNING", "Server", "server-4", komodo.ServerMem{
+			Server:  komodo.Server{ID: "server-4", Name: "db-server-1", Region: "ap-southeast-1"},
+			UsedGB:  14.5,
+			TotalGB: 16.0,
+		}),
+		sample("CRITICAL", "Server", "server-5", komodo.ServerDisk{
+			Server:  komodo.Server{ID: "server-5", Name: "storage-server", Region: "us-west-1"},
+			Path:    "/var/lib/docker",
+			UsedGB:  95.2,
+			TotalGB: 100.0,
+		}),
+		sample("CRITICAL", "Deployment", "deployment-3", komodo.ContainerStateChange{
+			ServerResource: komodo.ServerResource{ID: "deployment-3", Name: "web-app", ServerID: "server-1", ServerName: "production-server"},
+			From:           "running",
+			To:             "stopped",
+		}),
+		sample("OK", "Deployment", "deployment-1", komodo.DeploymentImageUpdateAvailable{
+			ServerResource: komodo.ServerResource{ID: "deployment-1", Name: "api-deployment", ServerID: "server-2", ServerName: "api-server"},
+			Image:          "myapp:v2.0.0",
+		}),
+		sample("OK", "Deployment", "deployment-2", komodo.DeploymentAutoUpdated{
+			ServerResource: komodo.ServerResource{ID: "deployment-2", Name: "worker-deployment", ServerID: "server-3", ServerName: "worker-server"},
+			Image:          "worker:v1.5.0",
+		}),
+		sample("WARNING", "Stack", "stack-1", komodo.StackStateChange{
+			ServerResource: komodo.ServerResource{ID: "stack-1", Name: "monitoring-stack", ServerID: "server-4", ServerName: "monitoring-server"},
+			From:           "running",
+			To:             "degraded",
+		}),
+		sample("OK", "Stack", "stack-2", komodo.StackImageUpdateAvailable{
+			ServerResource: komodo.ServerResource{ID: "stack-2", Name: "web-stack", ServerID: "server-5", ServerName: "web-server"},
+			Service:        "nginx",
+			Image:          "nginx:1.25.0",
+		}),
+		sample("OK", "Stack", "stack-3", komodo.StackAutoUpdated{
+			ServerResource: komodo.ServerResource{ID: "stack-3", Name: "app-stack", ServerID: "server-6", ServerName: "app-server"},
+			Images:         komodo.Strings{"frontend:v2.1.0", "backend:v3.0.0", "redis:7.0"},
+		}),
+		sample("CRITICAL", "Builder", "builder-1", komodo.AwsBuilderTerminationFailed{
+			InstanceID: "i-1234567890abcdef0",
+			Message:    "Unable to terminate instance: InvalidInstanceID.NotFound",
+		}),
+		sample("OK", "ResourceSync", "sync-1", komodo.ResourceSyncPendingUpdates{
+			Resource: komodo.Resource{ID: "sync-1", Name: "config-sync"},
+		}),
+		sample("CRITICAL", "Build", "build-1", komodo.BuildFailed{
+			Resource: komodo.Resource{ID: "build-1", Name: "frontend-build"},
+			Version:  komodo.Version{Major: 2, Minor: 5},
+		}),
+		sample("CRITICAL", "Repo", "repo-1", komodo.RepoBuildFailed{
+			Resource: komodo.Resource{ID: "repo-1", Name: "backend-repo"},
+		}),
+		sample("CRITICAL", "Procedure", "procedure-1", komodo.ProcedureFailed{
+			Resource: komodo.Resource{ID: "procedure-1", Name: "database-backup"},
+		}),
+		sample("CRITICAL", "Action", "action-1", komodo.ActionFailed{
+			Resource: komodo.Resource{ID: "action-1", Name: "deploy-to-production"},
+		}),
+		sample("OK", "Procedure", "procedure-2", komodo.ScheduleRun{
+			Resource:     komodo.Resource{ID: "procedure-2", Name: "nightly-backup"},
+			ResourceType: "Procedure",
+		}),
+		sample("WARNING", "System", "system", komodo.Custom{
+			Message: "Custom alert triggered",
+			Details: "This is a custom alert with additional details",
+		}),
+		sample("OK", "System", "system", komodo.None{}),
+	} {
+		sampleAlerts[a.Data.Type] = a
 	}
-	return m
 }
 
-// sampleAlerts provides example AlertInfo for each alert type
-var sampleAlerts = map[string]*komodo.AlertInfo{
-	"Test": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "test-target-1",
-			Type: "test",
-		},
-		Data: komodo.AlertData{
-			Type: "Test",
-			Payload: payloadMap(map[string]any{
-				"id":   "test-123",
-				"name": "Test Alert Example",
-			}),
-		},
-	},
-	"ServerVersionMismatch": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "warning",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "server-1",
-			Type: "server",
-		},
-		Data: komodo.AlertData{
-			Type: "ServerVersionMismatch",
-			Payload: payloadMap(map[string]any{
-				"id":             "server-1",
-				"name":           "production-server",
-				"region":         "us-west-2",
-				"server_version": "v1.2.3",
-				"core_version":   "v1.2.5",
-			}),
-		},
-	},
-	"ServerUnreachable": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "critical",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "server-2",
-			Type: "server",
-		},
-		Data: komodo.AlertData{
-			Type: "ServerUnreachable",
-			Payload: payloadMap(map[string]any{
-				"id":     "server-2",
-				"name":   "backup-server",
-				"region": "eu-central-1",
-				"err":    "connection timeout after 30s",
-			}),
-		},
-	},
-	"ServerCpu": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "warning",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "server-3",
-			Type: "server",
-		},
-		Data: komodo.AlertData{
-			Type: "ServerCpu",
-			Payload: payloadMap(map[string]any{
-				"id":         "server-3",
-				"name":       "api-server-1",
-				"region":     "us-east-1",
-				"percentage": 85.5,
-			}),
-		},
-	},
-	"ServerMem": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "warning",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "server-4",
-			Type: "server",
-		},
-		Data: komodo.AlertData{
-			Type: "ServerMem",
-			Payload: payloadMap(map[string]any{
-				"id":       "server-4",
-				"name":     "db-server-1",
-				"region":   "ap-southeast-1",
-				"used_gb":  14.5,
-				"total_gb": 16.0,
-			}),
-		},
-	},
-	"ServerDisk": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "critical",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "server-5",
-			Type: "server",
-		},
-		Data: komodo.AlertData{
-			Type: "ServerDisk",
-			Payload: payloadMap(map[string]any{
-				"id":       "server-5",
-				"name":     "storage-server",
-				"region":   "us-west-1",
-				"path":     "/var/lib/docker",
-				"used_gb":  95.2,
-				"total_gb": 100.0,
-			}),
-		},
-	},
-	"ContainerStateChange": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "container-1",
-			Type: "container",
-		},
-		Data: komodo.AlertData{
-			Type: "ContainerStateChange",
-			Payload: payloadMap(map[string]any{
-				"id":          "container-1",
-				"name":        "web-app",
-				"server_id":   "server-1",
-				"server_name": "production-server",
-				"from":        "running",
-				"to":          "stopped",
-			}),
-		},
-	},
-	"DeploymentImageUpdateAvailable": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "deployment-1",
-			Type: "deployment",
-		},
-		Data: komodo.AlertData{
-			Type: "DeploymentImageUpdateAvailable",
-			Payload: payloadMap(map[string]any{
-				"id":          "deployment-1",
-				"name":        "api-deployment",
-				"server_id":   "server-2",
-				"server_name": "api-server",
-				"image":       "myapp:v2.0.0",
-			}),
-		},
-	},
-	"DeploymentAutoUpdated": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "deployment-2",
-			Type: "deployment",
-		},
-		Data: komodo.AlertData{
-			Type: "DeploymentAutoUpdated",
-			Payload: payloadMap(map[string]any{
-				"id":          "deployment-2",
-				"name":        "worker-deployment",
-				"server_id":   "server-3",
-				"server_name": "worker-server",
-				"image":       "worker:v1.5.0",
-			}),
-		},
-	},
-	"StackStateChange": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "warning",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "stack-1",
-			Type: "stack",
-		},
-		Data: komodo.AlertData{
-			Type: "StackStateChange",
-			Payload: payloadMap(map[string]any{
-				"id":          "stack-1",
-				"name":        "monitoring-stack",
-				"server_id":   "server-4",
-				"server_name": "monitoring-server",
-				"from":        "running",
-				"to":          "degraded",
-			}),
-		},
-	},
-	"StackImageUpdateAvailable": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "stack-2",
-			Type: "stack",
-		},
-		Data: komodo.AlertData{
-			Type: "StackImageUpdateAvailable",
-			Payload: payloadMap(map[string]any{
-				"id":          "stack-2",
-				"name":        "web-stack",
-				"server_id":   "server-5",
-				"server_name": "web-server",
-				"service":     "nginx",
-				"image":       "nginx:1.25.0",
-			}),
-		},
-	},
-	"StackAutoUpdated": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "stack-3",
-			Type: "stack",
-		},
-		Data: komodo.AlertData{
-			Type: "StackAutoUpdated",
-			Payload: payloadMap(map[string]any{
-				"id":          "stack-3",
-				"name":        "app-stack",
-				"server_id":   "server-6",
-				"server_name": "app-server",
-				"images":      "frontend:v2.1.0, backend:v3.0.0, redis:7.0",
-			}),
-		},
-	},
-	"AwsBuilderTerminationFailed": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "critical",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "builder-1",
-			Type: "builder",
-		},
-		Data: komodo.AlertData{
-			Type: "AwsBuilderTerminationFailed",
-			Payload: payloadMap(map[string]any{
-				"instance_id": "i-1234567890abcdef0",
-				"message":     "Unable to terminate instance: InvalidInstanceID.NotFound",
-			}),
-		},
-	},
-	"ResourceSyncPendingUpdates": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "sync-1",
-			Type: "sync",
-		},
-		Data: komodo.AlertData{
-			Type: "ResourceSyncPendingUpdates",
-			Payload: payloadMap(map[string]any{
-				"id":   "sync-1",
-				"name": "config-sync",
-			}),
-		},
-	},
-	"BuildFailed": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "critical",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "build-1",
-			Type: "build",
-		},
-		Data: komodo.AlertData{
-			Type: "BuildFailed",
-			Payload: payloadMap(map[string]any{
-				"id":      "build-1",
-				"name":    "frontend-build",
-				"version": "v2.5.0",
-			}),
-		},
-	},
-	"RepoBuildFailed": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "critical",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "repo-1",
-			Type: "repo",
-		},
-		Data: komodo.AlertData{
-			Type: "RepoBuildFailed",
-			Payload: payloadMap(map[string]any{
-				"id":   "repo-1",
-				"name": "backend-repo",
-			}),
-		},
-	},
-	"ProcedureFailed": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "critical",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "procedure-1",
-			Type: "procedure",
-		},
-		Data: komodo.AlertData{
-			Type: "ProcedureFailed",
-			Payload: payloadMap(map[string]any{
-				"id":   "procedure-1",
-				"name": "database-backup",
-			}),
-		},
-	},
-	"ActionFailed": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "critical",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "action-1",
-			Type: "action",
-		},
-		Data: komodo.AlertData{
-			Type: "ActionFailed",
-			Payload: payloadMap(map[string]any{
-				"id":   "action-1",
-				"name": "deploy-to-production",
-			}),
-		},
-	},
-	"ScheduleRun": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "schedule-1",
-			Type: "schedule",
-		},
-		Data: komodo.AlertData{
-			Type: "ScheduleRun",
-			Payload: payloadMap(map[string]any{
-				"resource_type": "backup",
-				"id":            "schedule-1",
-				"name":          "nightly-backup",
-			}),
-		},
-	},
-	"Custom": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "custom-1",
-			Type: "custom",
-		},
-		Data: komodo.AlertData{
-			Type: "Custom",
-			Payload: payloadMap(map[string]any{
-				"message": "Custom alert triggered",
-				"details": "This is a custom alert with additional details",
-			}),
-		},
-	},
-	"None": {
-		Timestamp: time.Now().UnixMilli(),
-		Level:     "info",
-		Resolved:  false,
-		Target: komodo.AlertTarget{
-			ID:   "none-1",
-			Type: "none",
-		},
-		Data: komodo.AlertData{
-			Type:    "None",
-			Payload: komodo.Map{},
-		},
-	},
+// SampleTypes returns the alert types which have a sample alert, sorted.
+func SampleTypes() []string {
+	return slices.Sorted(maps.Keys(sampleAlerts))
+}
+
+// Sample returns a copy of the sample alert of typ issued now, or nil if
+// there is none.
+func Sample(typ string) *komodo.AlertInfo {
+	a, ok := sampleAlerts[typ]
+	if !ok {
+		return nil
+	}
+	ret := *a
+	ret.Timestamp = time.Now().UnixMilli()
+	return &ret
+}
+
+type noticeSample struct {
+	name     string
+	template string
+	// alert type the notice is about, which decides the format; empty if
+	// it is not about a single alert
+	typ  string
+	data any
+}
+
+// noticeSamples provides example data for templates which are not about a
+// specific alert type.
+func noticeSamples() []noticeSample {
+	resolved := *sampleAlerts["ServerCpu"]
+	resolved.Resolved = true
+	resolved.ResolveTimestamp = resolved.Timestamp + (12 * time.Minute).Milliseconds()
+	unknown := *sampleAlerts["ServerDisk"]
+	unknown.Data.Type = "SomeNewAlert"
+	return []noticeSample{
+		{"alert of unknown type", DefaultTemplate, unknown.Data.Type, &unknown},
+		{"resolved footer", ResolvedTemplate, "ServerCpu", &resolved},
+		{"flapping notice", FlappingTemplate, "StackStateChange", sampleAlerts["StackStateChange"]},
+		{"acknowledged footer", AckedTemplate, "ServerCpu", &Ack{Type: "ServerCpu", By: "@someone", At: time.Now()}},
+		{"digest", DigestTemplate, "", &Digest{
+			Group:  "host",
+			Labels: map[string]string{"server_name": "production-server"},
+			Alerts: []*komodo.AlertInfo{
+				sampleAlerts["ServerUnreachable"],
+				sampleAlerts["ContainerStateChange"],
+				&resolved,
+			},
+			Start: time.Now().Add(-30 * time.Second),
+			End:   time.Now(),
+		}},
+		{"report", ReportTemplate, "", &Report{
+			Name:  "daily",
+			Start: time.Now().Add(-24 * time.Hour),
+			End:   time.Now(),
+			Total: 42,
+			ByLevel: []Count{
+				{"WARNING", 30}, {"CRITICAL", 8}, {"OK", 4},
+			},
+			ByType: []Count{
+				{"ContainerStateChange", 25}, {"ServerCpu", 12}, {"ServerUnreachable", 5},
+			},
+			TopServers: []Count{
+				{"production-server", 20}, {"api-server-1", 12},
+			},
+			Resolved:          15,
+			MeanTimeToResolve: 17 * time.Minute,
+			Open: []ReportAlert{{
+				Type:     "ServerDisk",
+				Level:    "CRITICAL",
+				Target:   komodo.AlertTarget{Type: "Server", ID: "server-5"},
+				Name:     "storage-server",
+				IssuedAt: time.Now().Add(-3 * time.Hour),
+				Age:      3 * time.Hour,
+				AckedBy:  "@someone",
+			}},
+			ImageUpdates: []*komodo.AlertInfo{sampleAlerts["DeploymentImageUpdateAvailable"]},
+		}},
+		{"missed heartbeat", HeartbeatTemplate, "", &Heartbeat{
+			LastSeen: time.Now().Add(-15 * time.Minute),
+			Silent:   15 * time.Minute,
+			Interval: 15 * time.Minute,
+		}},
+		{"recovered heartbeat", HeartbeatTemplate, "", &Heartbeat{
+			Recovered: true,
+			LastSeen:  time.Now().Add(-42 * time.Minute),
+			Silent:    42 * time.Minute,
+			Interval:  15 * time.Minute,
+		}},
+	}
 }
 
 // Lint checks all templates for syntax errors and renders them with sample data
-func Lint(fs fs.FS, tz *time.Location) error {
+func Lint(fs fs.FS, tz *time.Location, format Format) error {
 	if fs == nil {
 		fs = Files
 	}
 
-	renderer := NewRenderer(fs, tz)
+	renderer := NewRenderer(fs, tz, format)
 	var hasError bool
 
 	// Try to render each sample alert
 	for typeName, sampleData := range sampleAlerts {
 		fmt.Printf("📝 Rendering %s...\n", typeName)
 		result, err := renderer.Render(sampleData)
+		if err == nil {
+			err = renderer.Format(typeName).lint(result)
+		}
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n\n", err)
+			hasError = true
+			continue
+		}
+
+		fmt.Println("✅ Success:")
+		fmt.Println("---")
+		fmt.Println(result)
+		fmt.Print("---\n\n")
+	}
+
+	for _, n := range noticeSamples() {
+		fmt.Printf("📝 Rendering %s...\n", n.name)
+		result, err := renderer.renderNotice(n.template, n.typ, n.data)
+		if err == nil {
+			err = renderer.set.Load().noticeFormat(n.template, n.typ).lint(result)
+		}
 		if err != nil {
 			fmt.Printf("❌ Error: %v\n\n", err)
 			hasError = true
@@ -437,7 +269,7 @@ func Lint(fs fs.FS, tz *time.Location) error {
 		fmt.Println("✅ Success:")
 		fmt.Println("---")
 		fmt.Println(result)
-		fmt.Println("---\n")
+		fmt.Print("---\n\n")
 	}
 
 	if hasError {
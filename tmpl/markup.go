@@ -0,0 +1,225 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package tmpl
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Check reports whether text is valid markup of f, so Telegram will not
+// reject it. Only Telegram formats are checked; text in other formats is
+// always valid.
+func (f Format) Check(text string) error {
+	switch f {
+	case FormatMarkdownV2:
+		return checkMarkdownV2(text)
+	case FormatHTML:
+		return checkHTML(text)
+	}
+	return nil
+}
+
+// leftoverMarkdownV2 matches MarkdownV2 bold text and escapes, which are not
+// markup in other formats and show up as is.
+var leftoverMarkdownV2 = regexp.MustCompile(`\*[^*\s](?:[^*\n]*[^*\s])?\*|\\[_*\[\]()~` + "`" + `>#+\-=|{}.!]`)
+
+// lint is Check plus a look for MarkdownV2 markup left in HTML or plain text,
+// usually from a template written for MarkdownV2 without the bold and escape
+// helpers.
+func (f Format) lint(text string) error {
+	if err := f.Check(text); err != nil {
+		return err
+	}
+	if f != FormatHTML && f != FormatPlain {
+		return nil
+	}
+	if loc := leftoverMarkdownV2.FindStringIndex(text); loc != nil {
+		return markupError(text, loc[0], "MarkdownV2 markup %q in %s output, use the bold and escape helpers", text[loc[0]:loc[1]], f)
+	}
+	return nil
+}
+
+// markupError tells where text is invalid, counting lines from 1.
+func markupError(text string, pos int, format string, args ...any) error {
+	line := strings.Count(text[:pos], "\n") + 1
+	return fmt.Errorf("line %d: "+format, append([]any{line}, args...)...)
+}
+
+// reservedMarkdownV2 must be escaped outside of entities.
+const reservedMarkdownV2 = "_*[]()~`>#+-=|{}.!"
+
+// toggle opens the entity marker, or closes it if it is the innermost open
+// entity.
+func toggle(stack []string, marker string) ([]string, bool) {
+	if n := len(stack); n > 0 && stack[n-1] == marker {
+		return stack[:n-1], true
+	}
+	if slices.Contains(stack, marker) {
+		return stack, false
+	}
+	return append(stack, marker), true
+}
+
+// checkMarkdownV2 follows https://core.telegram.org/bots/api#markdownv2-style
+func checkMarkdownV2(text string) error {
+	var stack []string
+	var starts []int
+	lineStart := true
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		atLineStart := lineStart
+		lineStart = c == '\n'
+
+		switch {
+		case c == '\\':
+			if i+1 >= len(text) {
+				return markupError(text, i, "nothing to escape after '\\'")
+			}
+			i++
+		case c == '`':
+			fence := "`"
+			if strings.HasPrefix(text[i:], "```") {
+				fence = "```"
+			}
+			end := closing(text, i+len(fence), fence)
+			if end < 0 {
+				return markupError(text, i, "%s is not closed", fence)
+			}
+			i = end + len(fence) - 1
+		case c == '*' || c == '~' || c == '_' || c == '|':
+			marker := string(c)
+			if (c == '_' || c == '|') && strings.HasPrefix(text[i:], marker+marker) {
+				marker += marker
+			} else if c == '|' {
+				return markupError(text, i, "character '|' is reserved and must be escaped with '\\'")
+			}
+			var ok bool
+			n := len(stack)
+			if stack, ok = toggle(stack, marker); !ok {
+				return markupError(text, i, "%s is closed before the entities inside it", marker)
+			}
+			if len(stack) > n {
+				starts = append(starts, i)
+			} else {
+				starts = starts[:len(starts)-1]
+			}
+			i += len(marker) - 1
+		case c == '[':
+			stack = append(stack, "[")
+			starts = append(starts, i)
+		case c == ']':
+			if len(stack) == 0 || stack[len(stack)-1] != "[" {
+				return markupError(text, i, "character ']' is reserved and must be escaped with '\\'")
+			}
+			if !strings.HasPrefix(text[i+1:], "(") {
+				return markupError(text, i, "link text must be followed by (url)")
+			}
+			end := closing(text, i+2, ")")
+			if end < 0 {
+				return markupError(text, i, "link URL is not closed")
+			}
+			stack = stack[:len(stack)-1]
+			starts = starts[:len(starts)-1]
+			i = end
+		case c == '>' && atLineStart:
+			// block quotation
+		case strings.IndexByte(reservedMarkdownV2, c) >= 0:
+			return markupError(text, i, "character '%c' is reserved and must be escaped with '\\'", c)
+		}
+	}
+
+	if len(stack) > 0 {
+		n := len(stack) - 1
+		return markupError(text, starts[n], "%s is not closed", stack[n])
+	}
+	return nil
+}
+
+// closing returns the index of the first unescaped marker in text from i, or
+// -1 if there is none.
+func closing(text string, i int, marker string) int {
+	for ; i < len(text); i++ {
+		if text[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], marker) {
+			return i
+		}
+	}
+	return -1
+}
+
+// htmlTags are tags supported by Telegram.
+var htmlTags = []string{
+	"b", "strong", "i", "em", "u", "ins", "s", "strike", "del", "span",
+	"tg-spoiler", "a", "code", "pre", "blockquote", "tg-emoji",
+}
+
+var (
+	htmlTag    = regexp.MustCompile(`^<(/?)([a-zA-Z-]+)(\s[^<>]*)?>`)
+	htmlEntity = regexp.MustCompile(`^&(lt|gt|amp|quot|#[0-9]+|#x[0-9a-fA-F]+);`)
+)
+
+// checkHTML follows https://core.telegram.org/bots/api#html-style
+func checkHTML(text string) error {
+	var stack []string
+	var starts []int
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '<':
+			m := htmlTag.FindStringSubmatch(text[i:])
+			if m == nil {
+				return markupError(text, i, "character '<' must be written as &lt;")
+			}
+			name := strings.ToLower(m[2])
+			if !slices.Contains(htmlTags, name) {
+				return markupError(text, i, "unsupported tag <%s>", name)
+			}
+			if m[1] == "" {
+				stack = append(stack, name)
+				starts = append(starts, i)
+			} else {
+				n := len(stack) - 1
+				if n < 0 || stack[n] != name {
+					return markupError(text, i, "unexpected end tag </%s>", name)
+				}
+				stack = stack[:n]
+				starts = starts[:n]
+			}
+			i += len(m[0]) - 1
+		case '>':
+			return markupError(text, i, "character '>' must be written as &gt;")
+		case '&':
+			m := htmlEntity.FindString(text[i:])
+			if m == "" {
+				return markupError(text, i, "character '&' must be written as &amp;")
+			}
+			i += len(m) - 1
+		}
+	}
+
+	if len(stack) > 0 {
+		n := len(stack) - 1
+		return markupError(text, starts[n], "<%s> is not closed", stack[n])
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package tmpl
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestLintLeftoverMarkdownV2(t *testing.T) {
+	cases := []struct {
+		format Format
+		text   string
+		ok     bool
+	}{
+		{FormatHTML, "Server: <b>web</b>\nCPU: <b>93.5%</b>", true},
+		{FormatHTML, "Server: *web*", false},
+		{FormatHTML, "CPU: 93\\.5%", false},
+		{FormatPlain, "Server: *web-1*", false},
+		{FormatPlain, "Server: web\\-1", false},
+		{FormatPlain, "2 * 3 * 4", true},
+		{FormatPlain, "path: C:\\data", true},
+		{FormatSlack, "Server: *web*", true},
+		{FormatMarkdownV2, "Server: *web\\-1*", true},
+	}
+	for _, c := range cases {
+		err := c.format.lint(c.text)
+		if (err == nil) != c.ok {
+			t.Errorf("%s %q: got error %v, want ok %v", c.format, c.text, err, c.ok)
+		}
+	}
+}
+
+func TestLintTemplates(t *testing.T) {
+	for _, f := range []Format{FormatMarkdownV2, FormatHTML, FormatPlain} {
+		if err := Lint(nil, time.UTC, f); err != nil {
+			t.Errorf("%s: %v", f, err)
+		}
+	}
+
+	// a template with hard-coded MarkdownV2 bold
+	fsys := fstest.MapFS{
+		"ServerCpu.txt": {Data: []byte(`Server: *{{ .Typed.Name | e }}*`)},
+	}
+	if err := Lint(fsys, time.UTC, FormatHTML); err == nil {
+		t.Error("MarkdownV2 bold in HTML template passes lint")
+	}
+}
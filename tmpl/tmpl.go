@@ -20,14 +20,18 @@ package tmpl
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"text/template"
 	"time"
 
-	"github.com/go-telegram/bot"
 	"github.com/raohwork/komodo-tg-alerter/komodo"
 	"github.com/rs/zerolog/log"
 )
@@ -35,59 +39,422 @@ import (
 //go:embed *.txt
 var Files embed.FS
 
+// ErrNoTemplate is returned by Render if there is no template for the alert type.
+var ErrNoTemplate = errors.New("no template for alert type")
+
 type Renderer struct {
-	fs fs.FS
-	tz *time.Location
+	fs     fs.FS
+	tz     *time.Location
+	format Format
+	// directory of fs if loaded from disk, watched by Watch
+	dir string
+
+	set atomic.Pointer[templateSet]
+}
+
+// templateSet is all templates of a Renderer parsed at once, once for each
+// format in use so included partials are escaped for the including template.
+type templateSet struct {
+	t map[Format]*template.Template
+	// format of templates without front matter
+	format Format
+	// format chosen by front matter, see parseFrontMatter
+	formats map[string]Format
+	// files failed to parse, rendering them returns the error
+	errs map[string]error
+	// source of each file, to tell what has changed on reload
+	sources map[string]string
 }
 
-func prepareTemplate(tz *time.Location) *template.Template {
+func prepareTemplate(tz *time.Location, format Format) *template.Template {
 	return template.New("").
 		Funcs(template.FuncMap{
 			"timefmt": func(t time.Time) string {
 				return t.In(tz).Format("2006-01-02 15:04:05")
 			},
-			"escape": bot.EscapeMarkdown,
-			"e":      bot.EscapeMarkdown, // short alias for escape
+			"escape": format.escape,
+			"e":      format.escape, // short alias for escape
 			"f": func(f float64) string {
-				return bot.EscapeMarkdown(fmt.Sprintf("%.4f", f))
+				return format.escape(fmt.Sprintf("%.4f", f))
+			},
+			"dur":  formatDuration,
+			"bold": format.bold,
+			"format": func() string {
+				return string(format)
 			},
 		})
 }
 
-func NewRenderer(fs fs.FS, tz *time.Location) *Renderer {
+// formatDuration formats d like "1h5m" or "42s".
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(time.Second).String()
+	}
+	s := d.Round(time.Minute).String()
+	s = strings.TrimSuffix(s, "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// NewRenderer creates a Renderer for templates in fs written in format.
+// The embedded templates are used if fs is nil. Templates are parsed here
+// once; files which fail to parse are logged and fail when rendered.
+func NewRenderer(fs fs.FS, tz *time.Location, format Format) *Renderer {
 	if fs == nil {
 		fs = Files
 	}
-	return &Renderer{fs: fs, tz: tz}
+	r := &Renderer{fs: fs, tz: tz, format: format}
+	set, err := r.load()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load templates")
+	}
+	r.set.Store(set)
+	return r
 }
 
 // NewRendererFromPath creates a Renderer using a custom template path.
 // If path is empty, it uses the embedded templates.
 // If path is not empty, it uses os.DirFS to load templates from the filesystem.
-func NewRendererFromPath(path string, tz *time.Location) *Renderer {
+func NewRendererFromPath(path string, tz *time.Location, format Format) *Renderer {
 	if path == "" {
-		return NewRenderer(nil, tz)
+		return NewRenderer(nil, tz, format)
+	}
+	r := NewRenderer(os.DirFS(path), tz, format)
+	r.dir = path
+	return r
+}
+
+// frontMatter is an optional first line of template files choosing the
+// format of the template, like {{/* format: html */}}.
+var frontMatter = regexp.MustCompile(`^\{\{-?\s*/\*\s*format:\s*(\S+)\s*\*/\s*-?\}\}[ \t]*\r?(\n|$)`)
+
+// parseFrontMatter returns the format chosen by the front matter of src, and
+// src without it.
+func parseFrontMatter(src string) (Format, string, error) {
+	m := frontMatter.FindStringSubmatch(src)
+	if m == nil {
+		return "", src, nil
+	}
+	f := Format(strings.ToLower(m[1]))
+	if !f.Valid() {
+		return "", src, fmt.Errorf("unknown format %q", m[1])
+	}
+	return f, src[len(m[0]):], nil
+}
+
+// load parses every template in r.fs, and embedded templates it does not
+// override. Files failing to parse are kept in errs, and returned joined as
+// error.
+func (r *Renderer) load() (*templateSet, error) {
+	set := &templateSet{
+		t:       map[Format]*template.Template{},
+		format:  r.format,
+		formats: map[string]Format{},
+		errs:    map[string]error{},
+		sources: map[string]string{},
+	}
+	custom, err := fs.Glob(r.fs, "*.txt")
+	if err != nil {
+		return set, err
+	}
+	embedded, _ := fs.Glob(Files, "*.txt")
+	src := map[string]fs.FS{}
+	for _, name := range embedded {
+		src[name] = Files
+	}
+	for _, name := range custom {
+		src[name] = r.fs
+	}
+
+	bodies := map[string]string{}
+	used := map[Format]bool{r.format: true}
+	for name, fsys := range src {
+		buf, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			set.errs[name] = fmt.Errorf("read template %s: %w", name, err)
+			continue
+		}
+		set.sources[name] = string(buf)
+		f, body, err := parseFrontMatter(string(buf))
+		if err != nil {
+			set.errs[name] = fmt.Errorf("parse template %s: %w", name, err)
+			continue
+		}
+		if f != "" {
+			set.formats[name] = f
+			used[f] = true
+		}
+		bodies[name] = body
+	}
+
+	for f := range used {
+		t := prepareTemplate(r.tz, f)
+		for name, body := range bodies {
+			if _, err := t.New(name).Parse(body); err != nil {
+				set.errs[name] = fmt.Errorf("parse template %s: %w", name, err)
+			}
+		}
+		set.t[f] = t
+	}
+
+	return set, set.err()
+}
+
+// err joins the errors of all files failed to parse.
+func (set *templateSet) err() error {
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(set.errs)) {
+		errs = append(errs, set.errs[name])
+	}
+	return errors.Join(errs...)
+}
+
+// Templates which are not about a specific alert type. Like all templates,
+// the embedded ones are used if the custom template path does not provide
+// them.
+const (
+	// used for alert types without their own template
+	DefaultTemplate = "_default.txt"
+	// partials included by alert templates with {{ template "_header.txt" . }}
+	HeaderTemplate = "_header.txt"
+	FooterTemplate = "_footer.txt"
+	// footer of resolved alerts
+	ResolvedTemplate = "_resolved.txt"
+	// sent once when an alert starts flapping
+	FlappingTemplate = "_flapping.txt"
+	// footer of alerts acknowledged by someone, rendered with Ack
+	AckedTemplate = "_acked.txt"
+	// alerts grouped into one message, rendered with Digest
+	DigestTemplate = "_digest.txt"
+	// scheduled summary of alert activity, rendered with Report
+	ReportTemplate = "_report.txt"
+	// Komodo missing or meeting the heartbeat deadline again, rendered with
+	// Heartbeat
+	HeartbeatTemplate = "_heartbeat.txt"
+)
+
+// Ack is the data of AckedTemplate.
+type Ack struct {
+	// type of the acknowledged alert, which decides the format
+	Type string
+	By   string
+	At   time.Time
+}
+
+// Digest is the data of DigestTemplate.
+type Digest struct {
+	// name of the group
+	Group string
+	// values of the labels alerts are grouped by
+	Labels map[string]string
+	Alerts []*komodo.AlertInfo
+	// when the first alert is received and the digest is sent
+	Start time.Time
+	End   time.Time
+}
+
+// Report is the data of ReportTemplate.
+type Report struct {
+	// name of the report in configuration
+	Name string
+	// period the report is about
+	Start time.Time
+	End   time.Time
+	// number of alerts received, by level and by type, most first
+	Total   int
+	ByLevel []Count
+	ByType  []Count
+	// servers with most alerts
+	TopServers []Count
+	// alerts resolved in the period, and the mean time from being issued to
+	// being resolved
+	Resolved          int
+	MeanTimeToResolve time.Duration
+	// alerts not resolved yet, oldest first
+	Open []ReportAlert
+	// latest image update alerts of targets which are not resolved yet
+	ImageUpdates []*komodo.AlertInfo
+}
+
+// Count is the number of alerts with something in common.
+type Count struct {
+	Name  string
+	Count int
+}
+
+// ReportAlert is an open alert in Report.
+type ReportAlert struct {
+	Type   string
+	Level  string
+	Target komodo.AlertTarget
+	// name of the target if known
+	Name     string
+	IssuedAt time.Time
+	Age      time.Duration
+	AckedBy  string
+}
+
+// Heartbeat is the data of HeartbeatTemplate.
+type Heartbeat struct {
+	// Komodo is heard from again after missing the deadline
+	Recovered bool
+	// when Komodo was last heard from, or kta started
+	LastSeen time.Time
+	// how long Komodo has been silent
+	Silent time.Duration
+	// how long Komodo may stay silent
+	Interval time.Duration
+}
+
+// has reports whether set has the file name, even if it failed to parse.
+func (set *templateSet) has(name string) bool {
+	_, ok := set.sources[name]
+	return ok
+}
+
+// template returns the file rendering alerts of typ.
+func (set *templateSet) template(typ string) string {
+	if name := typ + ".txt"; set.has(name) {
+		return name
+	}
+	return DefaultTemplate
+}
+
+// fileFormat returns the format of the file name.
+func (set *templateSet) fileFormat(name string) Format {
+	if f, ok := set.formats[name]; ok {
+		return f
 	}
-	return NewRenderer(os.DirFS(path), tz)
+	return set.format
+}
+
+// formatOf returns the format of alerts of typ.
+func (set *templateSet) formatOf(typ string) Format {
+	return set.fileFormat(set.template(typ))
+}
+
+// noticeFormat returns the format of the notice name about an alert of typ,
+// or of the file itself if it is not about a single alert.
+func (set *templateSet) noticeFormat(name, typ string) Format {
+	if typ == "" {
+		return set.fileFormat(name)
+	}
+	return set.formatOf(typ)
+}
+
+// execute renders the template name in format, failing if it cannot be
+// parsed.
+func (set *templateSet) execute(f Format, name string, data any) (string, error) {
+	if err, ok := set.errs[name]; ok {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := set.t[f].ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("execute template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// Err returns the errors of templates which failed to parse, nil if all of
+// them are loaded.
+func (r *Renderer) Err() error {
+	return r.set.Load().err()
+}
+
+// Format returns the format alerts of typ are rendered in, which is the
+// format of the Renderer unless the template chooses another one with front
+// matter. Notices about those alerts are rendered in the same format.
+func (r *Renderer) Format(typ string) Format {
+	return r.set.Load().formatOf(typ)
+}
+
+// renderNotice renders the notice template name about an alert of typ, or
+// about no alert in particular if typ is empty.
+func (r *Renderer) renderNotice(name, typ string, data any) (string, error) {
+	set := r.set.Load()
+	ret, err := set.execute(set.noticeFormat(name, typ), name, data)
+	return strings.TrimSpace(ret), err
+}
+
+// RenderResolved renders the footer attached to alerts when they are resolved.
+func (r *Renderer) RenderResolved(data *komodo.AlertInfo) (string, error) {
+	return r.renderNotice(ResolvedTemplate, data.Data.Type, data)
+}
+
+// RenderFlapping renders the notice sent when an alert starts flapping.
+func (r *Renderer) RenderFlapping(data *komodo.AlertInfo) (string, error) {
+	return r.renderNotice(FlappingTemplate, data.Data.Type, data)
 }
 
-func (r Renderer) Render(data *komodo.AlertInfo) (string, error) {
+// RenderAcked renders the footer attached to alerts when they are
+// acknowledged.
+func (r *Renderer) RenderAcked(data *Ack) (string, error) {
+	return r.renderNotice(AckedTemplate, data.Type, data)
+}
+
+// RenderDigest renders alerts grouped into one message.
+func (r *Renderer) RenderDigest(data *Digest) (string, error) {
+	return r.renderNotice(DigestTemplate, "", data)
+}
+
+// RenderReport renders a scheduled report.
+func (r *Renderer) RenderReport(data *Report) (string, error) {
+	return r.renderNotice(ReportTemplate, "", data)
+}
+
+// RenderHeartbeat renders a notice about Komodo being silent or back.
+func (r *Renderer) RenderHeartbeat(data *Heartbeat) (string, error) {
+	return r.renderNotice(HeartbeatTemplate, "", data)
+}
+
+// HeartbeatFormat returns the format heartbeat notices are rendered in, which
+// is the format of the Renderer unless HeartbeatTemplate chooses another one.
+func (r *Renderer) HeartbeatFormat() Format {
+	return r.set.Load().fileFormat(HeartbeatTemplate)
+}
+
+// ReportFormat returns the format reports are rendered in, which is the
+// format of the Renderer unless ReportTemplate chooses another one.
+func (r *Renderer) ReportFormat() Format {
+	return r.set.Load().fileFormat(ReportTemplate)
+}
+
+// DigestFormat returns the format digests are rendered in, which is the
+// format of the Renderer unless DigestTemplate chooses another one.
+func (r *Renderer) DigestFormat() Format {
+	return r.set.Load().fileFormat(DigestTemplate)
+}
+
+func (r *Renderer) Render(data *komodo.AlertInfo) (string, error) {
 	log.Info().
 		Interface("data", data).
 		Str("type", data.Data.Type).
 		Msg("rendering template")
 
-	typ := data.Data.Type
-	t, err := prepareTemplate(r.tz).ParseFS(r.fs, typ+".txt")
-	if err != nil {
-		return "", fmt.Errorf("parse template %s: %w", typ, err)
-	}
+	return r.render(r.set.Load(), data)
+}
 
-	var buf strings.Builder
-	err = t.ExecuteTemplate(&buf, typ+".txt", data)
+// RenderMessage renders data like Render, followed by the footer of
+// RenderResolved if data is resolved, as sent in a new message.
+func (r *Renderer) RenderMessage(data *komodo.AlertInfo) (string, error) {
+	text, err := r.Render(data)
+	if err != nil || !data.Resolved {
+		return text, err
+	}
+	footer, err := r.RenderResolved(data)
 	if err != nil {
-		return "", fmt.Errorf("execute template %s: %w", typ, err)
+		return "", err
 	}
+	return strings.TrimRight(text, "\n") + "\n\n" + footer, nil
+}
 
-	return buf.String(), nil
+func (r *Renderer) render(set *templateSet, data *komodo.AlertInfo) (string, error) {
+	typ := data.Data.Type
+	name := set.template(typ)
+	if !set.has(name) {
+		return "", fmt.Errorf("%w %s", ErrNoTemplate, typ)
+	}
+	return set.execute(set.formatOf(typ), name, data)
 }
@@ -0,0 +1,166 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package tmpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// reloadDelay is how long Watch waits for changes to settle, as editors often
+// write a file in several steps.
+const reloadDelay = 500 * time.Millisecond
+
+// watchedOps are the events in the template directory which trigger a reload.
+const watchedOps = fsnotify.Create | fsnotify.Write | fsnotify.Remove | fsnotify.Rename
+
+// verify renders sample alerts and notices with set and checks their markup.
+// Alert types set does not have a template for are skipped.
+func (r *Renderer) verify(set *templateSet) error {
+	var errs []error
+	for _, typ := range slices.Sorted(maps.Keys(sampleAlerts)) {
+		if !set.has(typ + ".txt") {
+			continue
+		}
+		text, err := r.render(set, sampleAlerts[typ])
+		if err == nil {
+			err = set.formatOf(typ).Check(text)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", typ, err))
+		}
+	}
+	for _, n := range noticeSamples() {
+		f := set.noticeFormat(n.template, n.typ)
+		text, err := set.execute(f, n.template, n.data)
+		if err == nil {
+			err = f.Check(text)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Reload parses the templates again and replaces the current ones, but only
+// if all of them parse and render sample alerts without error. Otherwise the
+// current templates are kept and the error is returned.
+func (r *Renderer) Reload() error {
+	set, err := r.load()
+	if err == nil {
+		err = r.verify(set)
+	}
+	if err != nil {
+		return err
+	}
+
+	old := r.set.Swap(set)
+	added, removed, changed := diffSources(old.sources, set.sources)
+	log.Info().
+		Str("path", r.dir).
+		Strs("added", added).
+		Strs("removed", removed).
+		Strs("changed", changed).
+		Msg("templates reloaded")
+	return nil
+}
+
+// diffSources returns the names of templates only in cur, only in prev and in
+// both with different sources, each sorted.
+func diffSources(prev, cur map[string]string) (added, removed, changed []string) {
+	for name, src := range cur {
+		old, ok := prev[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case old != src:
+			changed = append(changed, name)
+		}
+	}
+	for name := range prev {
+		if _, ok := cur[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	slices.Sort(added)
+	slices.Sort(removed)
+	slices.Sort(changed)
+	return
+}
+
+// Watch reloads templates when files in the template directory change until
+// ctx is done. Failed reloads are logged and the current templates are kept.
+// It does nothing if the templates are not loaded from a directory.
+//
+// Any change in the directory triggers a reload, not only changes of .txt
+// files: Kubernetes ConfigMap and Secret volumes are updated by swapping a
+// ..data symlink, and some editors rename a temporary file into place.
+func (r *Renderer) Watch(ctx context.Context) error {
+	if r.dir == "" {
+		return nil
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watch templates: %w", err)
+	}
+	if err := w.Add(r.dir); err != nil {
+		w.Close()
+		return fmt.Errorf("watch templates: %w", err)
+	}
+
+	go func() {
+		defer w.Close()
+		timer := time.NewTimer(reloadDelay)
+		timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Op&watchedOps != 0 {
+					timer.Reset(reloadDelay)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Warn().Err(err).Str("path", r.dir).Msg("error watching templates")
+			case <-timer.C:
+				if err := r.Reload(); err != nil {
+					log.Error().Err(err).Str("path", r.dir).
+						Msg("templates not reloaded, keeping current ones")
+				}
+			}
+		}
+	}()
+	log.Info().Str("path", r.dir).Msg("watching templates for changes")
+	return nil
+}
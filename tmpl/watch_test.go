@@ -0,0 +1,143 @@
+/*
+Copyright © 2026 Ronmi Ren
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package tmpl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTemplate(t *testing.T, dir, name, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// renderCpu renders the ServerCpu sample with r.
+func renderCpu(t *testing.T, r *Renderer) string {
+	t.Helper()
+	text, err := r.Render(Sample("ServerCpu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return text
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, "ServerCpu.txt", `CPU of {{ .Typed.Name | e }}`)
+	r := NewRendererFromPath(dir, time.UTC, FormatPlain)
+	if text := renderCpu(t, r); text != "CPU of api-server-1" {
+		t.Fatalf("rendered %q before reload", text)
+	}
+
+	writeTemplate(t, dir, "ServerCpu.txt", `Load of {{ .Typed.Name | e }}`)
+	if err := r.Reload(); err != nil {
+		t.Fatalf("valid edit is rejected: %v", err)
+	}
+	if text := renderCpu(t, r); text != "Load of api-server-1" {
+		t.Errorf("rendered %q after a valid edit, want the new template", text)
+	}
+
+	for name, src := range map[string]string{
+		"parse error":  `Load of {{ .Typed.Name `,
+		"render error": `Load of {{ index .Typed.Name 100 }}`,
+		"markup error": "{{/* format: html */}}\n<b>{{ .Typed.Name | e }}",
+	} {
+		writeTemplate(t, dir, "ServerCpu.txt", src)
+		if err := r.Reload(); err == nil || !strings.Contains(err.Error(), "ServerCpu") {
+			t.Errorf("%s: Reload returned %v, want an error about ServerCpu", name, err)
+		}
+		if text := renderCpu(t, r); text != "Load of api-server-1" {
+			t.Errorf("%s: rendered %q, want the previous template kept", name, text)
+		}
+	}
+}
+
+func TestDiffSources(t *testing.T) {
+	prev := map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c", "d.txt": "d"}
+	cur := map[string]string{"a.txt": "a", "b.txt": "B", "e.txt": "e", "0.txt": "0"}
+	added, removed, changed := diffSources(prev, cur)
+	for _, c := range []struct {
+		name      string
+		got, want []string
+	}{
+		{"added", added, []string{"0.txt", "e.txt"}},
+		{"removed", removed, []string{"c.txt", "d.txt"}},
+		{"changed", changed, []string{"b.txt"}},
+	} {
+		if !slices.Equal(c.got, c.want) {
+			t.Errorf("%s is %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	added, removed, changed = diffSources(prev, prev)
+	if added != nil || removed != nil || changed != nil {
+		t.Errorf("same sources differ: %v, %v, %v", added, removed, changed)
+	}
+}
+
+// TestWatchSymlinkSwap updates templates like a Kubernetes ConfigMap volume:
+// files are symlinks into ..data, which is replaced by renaming a new
+// symlink over it.
+func TestWatchSymlinkSwap(t *testing.T) {
+	dir := t.TempDir()
+	version := func(name, src string) {
+		t.Helper()
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeTemplate(t, filepath.Join(dir, name), "ServerCpu.txt", src)
+	}
+	version("..v1", `CPU of {{ .Typed.Name | e }}`)
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("..data/ServerCpu.txt", filepath.Join(dir, "ServerCpu.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRendererFromPath(dir, time.UTC, FormatPlain)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := r.Watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	version("..v2", `Load of {{ .Typed.Name | e }}`)
+	if err := os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * reloadDelay)
+	for renderCpu(t, r) != "Load of api-server-1" {
+		if time.Now().After(deadline) {
+			t.Fatal("templates are not reloaded after ..data is swapped")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}